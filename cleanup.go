@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+
+	"data-processing-spark-submit/upload"
+)
+
+// Cleanup deletion of the objects uploaded for a job, once it's over
+type Cleanup struct {
+	Storage   upload.StorageI
+	Container string
+	// Objects created by the upload for the job, the ones which already existed excluded
+	Objects []string
+	// OnlyCompleted delete the objects only when the job is COMPLETED, to be able to investigate its failure
+	OnlyCompleted bool
+}
+
+// Run delete the uploaded objects according to the final status of the job
+func (c *Cleanup) Run(job *JobStatus) {
+	if c == nil || len(c.Objects) == 0 {
+		return
+	}
+
+	if c.OnlyCompleted && job.Status != JobStatusCOMPLETED {
+		log.Printf("Job is %s, the uploaded files are kept", job.Status)
+		return
+	}
+	c.delete()
+}
+
+// Abort delete the uploaded objects when the upload or the submission of the job failed
+func (c *Cleanup) Abort() {
+	if c == nil || len(c.Objects) == 0 {
+		return
+	}
+	c.delete()
+}
+
+// delete the uploaded objects, errors are only logged
+func (c *Cleanup) delete() {
+	if err := c.Storage.Delete(c.Container, c.Objects); err != nil {
+		log.Printf("Unable to delete the uploaded files: %s", err)
+		return
+	}
+	log.Printf("%d uploaded file(s) deleted", len(c.Objects))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"data-processing-spark-submit/upload"
+)
+
+// mockStorage storage recording the deleted objects
+type mockStorage struct {
+	upload.StorageI
+	deleted []string
+	err     error
+}
+
+func (s *mockStorage) Delete(dest string, objects []string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.deleted = append(s.deleted, objects...)
+	return nil
+}
+
+func TestCleanupRun(t *testing.T) {
+	storage := &mockStorage{}
+	cleanup := &Cleanup{Storage: storage, Container: "odp", Objects: []string{"jobs/main.py", "jobs/helpers.zip"}}
+
+	cleanup.Run(&JobStatus{ID: JobID, Status: JobStatusFAILED})
+	if len(storage.deleted) != 2 || storage.deleted[0] != "jobs/main.py" {
+		t.Errorf("unexpected deleted objects %q", storage.deleted)
+	}
+}
+
+func TestCleanupRunOnlyCompleted(t *testing.T) {
+	storage := &mockStorage{}
+	cleanup := &Cleanup{Storage: storage, Container: "odp", Objects: []string{"jobs/main.py"}, OnlyCompleted: true}
+
+	cleanup.Run(&JobStatus{ID: JobID, Status: JobStatusFAILED})
+	if len(storage.deleted) != 0 {
+		t.Error("uploaded files of a failed job must be kept")
+	}
+
+	cleanup.Run(&JobStatus{ID: JobID, Status: JobStatusCOMPLETED})
+	if len(storage.deleted) != 1 {
+		t.Errorf("unexpected deleted objects %q", storage.deleted)
+	}
+}
+
+func TestCleanupAbort(t *testing.T) {
+	storage := &mockStorage{}
+	cleanup := &Cleanup{Storage: storage, Container: "odp", Objects: []string{"jobs/main.py"}, OnlyCompleted: true}
+
+	// no job to investigate
+	cleanup.Abort()
+	if len(storage.deleted) != 1 {
+		t.Errorf("unexpected deleted objects %q", storage.deleted)
+	}
+
+	cleanup = nil
+	cleanup.Abort()
+}
+
+func TestCleanupRunNil(t *testing.T) {
+	var cleanup *Cleanup
+	cleanup.Run(&JobStatus{ID: JobID, Status: JobStatusCOMPLETED})
+
+	// deletion errors are only logged
+	cleanup = &Cleanup{Storage: &mockStorage{err: errors.New("forbidden")}, Objects: []string{"main.py"}}
+	cleanup.Run(&JobStatus{ID: JobID, Status: JobStatusCOMPLETED})
+}
@@ -0,0 +1,405 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	arg "github.com/alexflint/go-arg"
+	ini "gopkg.in/ini.v1"
+)
+
+type (
+	// CommandArgs arguments shared by the commands working on existing jobs
+	CommandArgs struct {
+		ProjectID string  `arg:"env:OS_PROJECT_ID" help:"Openstack ProjectID (can be set with ENV vars OS_PROJECT_ID)"`
+		Config    *string `arg:"--config" help:"Path to the configuration.ini file [default: configuration.ini]"`
+		Conf      *string `arg:"--conf" help:"Deprecated alias of --config"`
+		Output    string  `arg:"--output" default:"text" help:"Output format: text or json (newline-delimited json events)"`
+	}
+
+	// JobCommandArgs arguments of the commands working on a single job
+	JobCommandArgs struct {
+		CommandArgs
+		JobID string `arg:"positional,required" help:"ID of the job"`
+	}
+
+	// LogFilterArgs arguments of the commands printing the logs of a job
+	LogFilterArgs struct {
+		LogLevel string `arg:"--log-level" help:"Least severe level of the printed log lines: TRACE, DEBUG, INFO, WARN, ERROR or FATAL, the lines without level having the one of the previous line"`
+		Grep     string `arg:"--grep" help:"Regular expression the printed log lines must match"`
+	}
+
+	// LogFileArgs arguments of the commands following a job, to write its logs to a file
+	LogFileArgs struct {
+		LogFile         string `arg:"--log-file" help:"Write the log lines of the job to the given file, the console only showing the status changes and the ERROR lines, unless --log-level is set"`
+		LogFileMaxSize  *int   `arg:"--log-file-max-size" help:"Size in MiB from which the log file is rotated, 0 to never rotate it [default: 100]"`
+		LogFileMaxFiles *int   `arg:"--log-file-max-files" help:"Number of rotated log files kept, 0 to keep none [default: 5]"`
+		LogFileCompress bool   `arg:"--log-file-compress" help:"Compress the rotated log files with gzip"`
+		LogTail         *int   `arg:"--log-tail" help:"With --log-file, number of the last log lines printed when the job doesn't complete, 0 to print none [default: 50]"`
+	}
+
+	// LogsCommandArgs arguments of the logs command
+	LogsCommandArgs struct {
+		JobCommandArgs
+		LogFilterArgs
+		Download   string `arg:"--download" help:"Download the logs of the job from the object storage into the given directory"`
+		Decompress bool   `arg:"--decompress" help:"With --download, decompress the gzip compressed logs"`
+		Concat     bool   `arg:"--concat" help:"With --download, also concatenate the driver and executor logs, decompressed, in <job id>.log"`
+	}
+
+	// AttachCommandArgs arguments of the attach command
+	AttachCommandArgs struct {
+		JobCommandArgs
+		LogFilterArgs
+		LogFileArgs
+		Replay   bool   `arg:"--replay" help:"Replay the logs of the job from its start"`
+		From     string `arg:"--from" help:"Replay the logs of the job from this date (RFC3339 eg. \"2022-10-07T09:00:00Z\")"`
+		OnSignal string `arg:"--on-signal" default:"ask" help:"Action on SIGINT/SIGTERM: ask (kill without TTY), kill or detach"`
+	}
+
+	// ListCommandArgs arguments of the list command
+	ListCommandArgs struct {
+		CommandArgs
+		Status       string `arg:"--status" help:"Comma-delimited list of the status of the jobs to list (eg. \"RUNNING,PENDING\")"`
+		Name         string `arg:"--name" help:"Glob pattern of the name of the jobs to list (eg. \"etl-*\")"`
+		From         string `arg:"--from" help:"List the jobs created from this date (RFC3339 eg. \"2022-10-07T09:00:00Z\" or \"2022-10-07\")"`
+		To           string `arg:"--to" help:"List the jobs created until this date (RFC3339 eg. \"2022-10-07T18:00:00Z\" or \"2022-10-07\")"`
+		SparkVersion string `arg:"--spark-version" help:"Version of spark of the jobs to list"`
+	}
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+
+	dayLayout = "2006-01-02"
+)
+
+// JobStatuses all the status a job can have
+var JobStatuses = []string{
+	JobStatusUNKNOWN,
+	JobStatusPENDING,
+	JobStatusSUBMITTED,
+	JobStatusRUNNING,
+	JobStatusCANCELLING,
+	JobStatusFAILED,
+	JobStatusTERMINATED,
+	JobStatusCOMPLETED,
+}
+
+// commands available, the job submission being the default one
+var commands = map[string]func(arguments []string){
+	"submit": submitCommand,
+	"status": statusCommand,
+	"logs":   logsCommand,
+	"kill":   killCommand,
+	"list":   listCommand,
+	"attach": attachCommand,
+}
+
+// statusCommand print the status of a job
+func statusCommand(arguments []string) {
+	cmdArgs := &JobCommandArgs{}
+	parser := mustParseCommand("status", cmdArgs, arguments)
+	client := cmdArgs.mustInitClient(parser)
+
+	job, err := client.GetStatus(cmdArgs.ProjectID, cmdArgs.JobID)
+	if err != nil {
+		Fatalf(ExitCodeAPI, "Unable to retrieve status for job: %s", err)
+	}
+
+	if out.JSON() {
+		out.Job(job)
+		return
+	}
+
+	if err := PrintStatus(os.Stdout, job); err != nil {
+		Fatalf(ExitCodeError, "Unable to print status for job: %s", err)
+	}
+}
+
+// logsCommand print the logs of a job, or download them from the object storage
+func logsCommand(arguments []string) {
+	cmdArgs := &LogsCommandArgs{}
+	parser := mustParseCommand("logs", cmdArgs, arguments)
+	if cmdArgs.Download == "" && (cmdArgs.Decompress || cmdArgs.Concat) {
+		parser.Fail("--decompress and --concat can only be used with --download")
+	}
+
+	conf, protocols := cmdArgs.mustLoadConf(parser)
+	cmdArgs.mustSetLogFilter(parser, "")
+	client := mustInitClient(conf, cmdArgs.ProjectID)
+
+	if cmdArgs.Download == "" {
+		// the API returns the logs by pages
+		if err := PrintLogPages(client, cmdArgs.JobID, 0); err != nil {
+			Fatalf(ExitCodeAPI, "Unable fetch job log: %s", err)
+		}
+		return
+	}
+
+	jobLog, err := client.GetLog(cmdArgs.ProjectID, cmdArgs.JobID, "")
+	if err != nil {
+		Fatalf(ExitCodeAPI, "Unable fetch job log: %s", err)
+	}
+	if jobLog.LogsAddress == "" {
+		Fatalf(ExitCodeError, "Unable to download the logs: no logs address for job %s, is it over?", cmdArgs.JobID)
+	}
+	storage, err := LogsStorage(conf, protocols)
+	if err != nil {
+		Fatalf(ExitCodeConfig, "Unable to download the logs: %s", err)
+	}
+
+	logsDownload := &LogsDownload{
+		Storage:    storage,
+		Dir:        cmdArgs.Download,
+		Decompress: cmdArgs.Decompress,
+		Concat:     cmdArgs.Concat,
+	}
+	files, err := logsDownload.Download(cmdArgs.JobID, jobLog.LogsAddress)
+	if err != nil {
+		Fatalf(ExitCodeError, "Unable to download the logs: %s", err)
+	}
+	out.LogsDownloaded(cmdArgs.JobID, files)
+}
+
+// killCommand kill a job
+func killCommand(arguments []string) {
+	cmdArgs := &JobCommandArgs{}
+	parser := mustParseCommand("kill", cmdArgs, arguments)
+	client := cmdArgs.mustInitClient(parser)
+
+	if err := client.Kill(cmdArgs.ProjectID, cmdArgs.JobID); err != nil {
+		Fatalf(ExitCodeAPI, "Job not killed: %s", err)
+	}
+	out.Killed(cmdArgs.JobID)
+}
+
+// attachCommand follow an already submitted job until it ends and exit with its return code
+func attachCommand(arguments []string) {
+	cmdArgs := &AttachCommandArgs{}
+	parser := mustParseCommand("attach", cmdArgs, arguments)
+
+	logsFrom, err := cmdArgs.LogsFrom(time.Now())
+	if err != nil {
+		parser.Fail(err.Error())
+	}
+
+	if !inTheList(cmdArgs.OnSignal, OnSignalPolicies) {
+		parser.Fail("Invalid value for --on-signal. It must be one of " + strings.Join(OnSignalPolicies, ", "))
+	}
+
+	if !ValidLogFileSettings(cmdArgs.LogFileMaxSize, cmdArgs.LogFileMaxFiles, cmdArgs.LogTail) {
+		parser.Fail("Invalid value for --log-file-max-size, --log-file-max-files or --log-tail. It must be positive")
+	}
+
+	client := cmdArgs.mustInitClient(parser)
+	cmdArgs.mustSetLogFilter(parser, cmdArgs.LogFile)
+	if cmdArgs.LogFile != "" {
+		logFile := NewLogFile(cmdArgs.LogFile, cmdArgs.LogFileMaxSize, cmdArgs.LogFileMaxFiles, cmdArgs.LogFileCompress, cmdArgs.LogTail)
+		if err := logFile.Open(); err != nil {
+			Fatalf(ExitCodeConfig, "Unable to open the log file: %s", err)
+		}
+		out.SetLogFile(logFile)
+	}
+	client.JobID = cmdArgs.JobID
+	client.LogsFrom = logsFrom
+
+	job, err := client.GetStatus(cmdArgs.ProjectID, cmdArgs.JobID)
+	if err != nil {
+		Fatalf(ExitCodeAPI, "Unable to retrieve status for job: %s", err)
+	}
+	out.Status(job)
+
+	Wait(client, job, cmdArgs.OnSignal, nil, nil)
+}
+
+// LogsFrom lower bound of the logs to stream: none when replaying, the given date or now by default
+func (a *AttachCommandArgs) LogsFrom(now time.Time) (string, error) {
+	switch {
+	case a.Replay && a.From != "":
+		return "", fmt.Errorf("--replay and --from can't be used together")
+	case a.Replay:
+		return "", nil
+	case a.From != "":
+		from, err := parseDate(a.From)
+		if err != nil {
+			return "", fmt.Errorf("invalid value for --from: %s", err)
+		}
+		return from.UTC().Format(LogsFromLayout), nil
+	default:
+		return now.UTC().Format(LogsFromLayout), nil
+	}
+}
+
+// listCommand print the jobs of the project matching the filters
+func listCommand(arguments []string) {
+	cmdArgs := &ListCommandArgs{}
+	parser := mustParseCommand("list", cmdArgs, arguments)
+
+	filter, err := cmdArgs.Filter()
+	if err != nil {
+		parser.Fail(err.Error())
+	}
+
+	client := cmdArgs.mustInitClient(parser)
+
+	jobs, err := client.ListJobs(cmdArgs.ProjectID, filter)
+	if err != nil {
+		Fatalf(ExitCodeAPI, "Unable to list jobs: %s", err)
+	}
+
+	if out.JSON() {
+		for _, job := range jobs {
+			out.Job(job)
+		}
+		return
+	}
+
+	if err := PrintJobs(os.Stdout, jobs); err != nil {
+		Fatalf(ExitCodeError, "Unable to print jobs: %s", err)
+	}
+}
+
+// Filter build the job filter from the arguments
+func (a *ListCommandArgs) Filter() (*JobFilter, error) {
+	filter := &JobFilter{
+		Name:          a.Name,
+		EngineVersion: a.SparkVersion,
+	}
+
+	if a.Status != "" {
+		for _, status := range strings.Split(a.Status, ",") {
+			status = strings.ToUpper(strings.TrimSpace(status))
+			if !inTheList(status, JobStatuses) {
+				return nil, fmt.Errorf("invalid value for --status: %s isn't one of %s", status, strings.Join(JobStatuses, ", "))
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+
+	if a.Name != "" {
+		if _, err := path.Match(a.Name, ""); err != nil {
+			return nil, fmt.Errorf("invalid value for --name: %s", err)
+		}
+	}
+
+	var err error
+	if a.From != "" {
+		if filter.CreatedAfter, err = parseDate(a.From); err != nil {
+			return nil, fmt.Errorf("invalid value for --from: %s", err)
+		}
+	}
+	if a.To != "" {
+		if filter.CreatedBefore, err = parseDate(a.To); err != nil {
+			return nil, fmt.Errorf("invalid value for --to: %s", err)
+		}
+		// a day includes all the jobs created during it
+		if len(a.To) == len(dayLayout) {
+			filter.CreatedBefore = filter.CreatedBefore.Add(24*time.Hour - time.Nanosecond)
+		}
+	}
+
+	return filter, nil
+}
+
+// parseDate parse a RFC3339 date, or a day (eg. "2022-10-07") at midnight UTC
+func parseDate(date string) (time.Time, error) {
+	if t, err := time.Parse(dayLayout, date); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, date)
+}
+
+// mustParseCommand parse the arguments of a command and exit upon failure, as arg.MustParse does
+func mustParseCommand(name string, dest interface{}, arguments []string) *arg.Parser {
+	parser, err := arg.NewParser(arg.Config{Program: filepath.Base(os.Args[0]) + " " + name}, dest)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(ExitCodeUsage)
+	}
+
+	err = parser.Parse(arguments)
+	switch {
+	case err == arg.ErrHelp:
+		parser.WriteHelp(os.Stdout)
+		os.Exit(0)
+	case err != nil:
+		parser.Fail(err.Error())
+	}
+	return parser
+}
+
+// mustInitClient set the output format, load the configuration.ini and create the API client,
+// the projectid of the [spark] section is used when not given
+func (a *CommandArgs) mustInitClient(p *arg.Parser) *Client {
+	conf, _ := a.mustLoadConf(p)
+	return mustInitClient(conf, a.ProjectID)
+}
+
+// mustLoadConf set the output format, load the configuration.ini and check that the project is known,
+// it returns the sections of the configuration and the storage protocols configured
+func (a *CommandArgs) mustLoadConf(p *arg.Parser) (map[string]*ini.Section, []string) {
+	if err := out.SetFormat(a.Output); err != nil {
+		p.Fail(err.Error())
+	}
+
+	if a.Config == nil {
+		a.Config = a.Conf
+	}
+	if a.Config == nil {
+		a.Config = &defaultConfigPath
+	}
+
+	conf, protocols := mustLoadConf(*a.Config)
+	if section, ok := conf["spark"]; ok && a.ProjectID == "" {
+		a.ProjectID = section.Key("projectid").String()
+	}
+
+	if a.ProjectID == "" {
+		p.Fail("--projectid is required")
+	}
+
+	return conf, protocols
+}
+
+// mustSetLogFilter filter the printed log lines, once the output format is set
+func (a *LogFilterArgs) mustSetLogFilter(p *arg.Parser, logFile string) {
+	filter, err := NewLogFilter(ConsoleLogLevel(a.LogLevel, logFile), a.Grep, out.Colored())
+	if err != nil {
+		p.Fail(err.Error())
+	}
+	out.SetLogFilter(filter)
+}
+
+// PrintStatus print the status of the given job
+func PrintStatus(w io.Writer, job *JobStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", job.ID)
+	fmt.Fprintf(tw, "Name:\t%s\n", job.Name)
+	fmt.Fprintf(tw, "Status:\t%s\n", job.Status)
+	fmt.Fprintf(tw, "Region:\t%s\n", job.Region)
+	fmt.Fprintf(tw, "Engine:\t%s %s\n", job.Engine, job.EngineVersion)
+	fmt.Fprintf(tw, "Container:\t%s\n", job.ContainerName)
+	fmt.Fprintf(tw, "Creation date:\t%s\n", job.CreationDate)
+	fmt.Fprintf(tw, "Start date:\t%s\n", job.StartDate)
+	fmt.Fprintf(tw, "End date:\t%s\n", job.EndDate)
+	fmt.Fprintf(tw, "Return code:\t%d\n", job.ReturnCode)
+	return tw.Flush()
+}
+
+// PrintJobs print the given jobs as a table
+func PrintJobs(w io.Writer, jobs []*JobStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSTATUS\tSPARK VERSION\tCREATION DATE\tRETURN CODE")
+	for _, job := range jobs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\n", job.ID, job.Name, job.Status, job.EngineVersion, job.CreationDate, job.ReturnCode)
+	}
+	return tw.Flush()
+}
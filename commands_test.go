@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMustParseCommand(t *testing.T) {
+	cmdArgs := &JobCommandArgs{}
+	mustParseCommand("status", cmdArgs, []string{"--projectid", ProjectID, "--config", "testdata/configuration.ini", JobID})
+
+	if cmdArgs.JobID != JobID {
+		t.Fail()
+	}
+
+	if cmdArgs.ProjectID != ProjectID {
+		t.Fail()
+	}
+
+	if cmdArgs.Config == nil || *cmdArgs.Config != "testdata/configuration.ini" {
+		t.Fail()
+	}
+
+	// deprecated alias of --config
+	cmdArgs = &JobCommandArgs{}
+	mustParseCommand("status", cmdArgs, []string{"--conf", "testdata/configuration.ini", JobID})
+	if cmdArgs.Conf == nil || *cmdArgs.Conf != "testdata/configuration.ini" {
+		t.Fail()
+	}
+}
+
+func TestPrintStatus(t *testing.T) {
+	job := &JobStatus{
+		ID:            JobID,
+		Name:          "hello",
+		Region:        "GRA",
+		Engine:        "spark",
+		EngineVersion: "2.4.3",
+		Status:        JobStatusCOMPLETED,
+		ReturnCode:    1,
+	}
+
+	var out bytes.Buffer
+	if err := PrintStatus(&out, job); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"ID:             " + JobID, "Status:         COMPLETED", "Engine:         spark 2.4.3", "Return code:    1"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("%q not found in %q", expected, out.String())
+		}
+	}
+}
+
+func TestListCommandArgsFilter(t *testing.T) {
+	cmdArgs := &ListCommandArgs{
+		Status:       "running, pending",
+		Name:         "etl-*",
+		From:         "2022-10-07T09:00:00Z",
+		To:           "2022-10-08",
+		SparkVersion: "3.3.0",
+	}
+
+	filter, err := cmdArgs.Filter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(filter.Statuses) != 2 || filter.Statuses[0] != JobStatusRUNNING || filter.Statuses[1] != JobStatusPENDING {
+		t.Fail()
+	}
+
+	if filter.CreatedAfter.Format(time.RFC3339) != "2022-10-07T09:00:00Z" {
+		t.Fail()
+	}
+
+	if filter.CreatedBefore.Format(time.RFC3339) != "2022-10-08T23:59:59Z" {
+		t.Fail()
+	}
+
+	if filter.Name != "etl-*" || filter.EngineVersion != "3.3.0" {
+		t.Fail()
+	}
+}
+
+func TestListCommandArgsFilterErr(t *testing.T) {
+	for _, cmdArgs := range []*ListCommandArgs{
+		{Status: "RUNNING,DONE"},
+		{Name: "etl-["},
+		{From: "yesterday"},
+		{To: "07/10/2022"},
+	} {
+		if _, err := cmdArgs.Filter(); err == nil {
+			t.Errorf("%+v should be invalid", cmdArgs)
+		}
+	}
+}
+
+func TestPrintJobs(t *testing.T) {
+	jobs := []*JobStatus{
+		{
+			ID:            JobID,
+			Name:          "hello",
+			Status:        JobStatusRUNNING,
+			EngineVersion: "3.3.0",
+			CreationDate:  "2022-10-07T09:01:09Z",
+		},
+	}
+
+	var out bytes.Buffer
+	if err := PrintJobs(&out, jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	if !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], JobID) || !strings.Contains(lines[1], JobStatusRUNNING) {
+		t.Fail()
+	}
+}
+
+func TestAttachCommandArgsLogsFrom(t *testing.T) {
+	now := time.Date(2022, 10, 7, 11, 0, 0, 0, time.FixedZone("CEST", 2*3600))
+
+	from, err := (&AttachCommandArgs{}).LogsFrom(now)
+	if err != nil || from != "2022-10-07T09:00:00.000" {
+		t.Errorf("unexpected default from %s: %v", from, err)
+	}
+
+	from, err = (&AttachCommandArgs{Replay: true}).LogsFrom(now)
+	if err != nil || from != "" {
+		t.Errorf("unexpected replay from %s: %v", from, err)
+	}
+
+	from, err = (&AttachCommandArgs{From: "2022-10-07T08:30:15.250Z"}).LogsFrom(now)
+	if err != nil || from != "2022-10-07T08:30:15.250" {
+		t.Errorf("unexpected from %s: %v", from, err)
+	}
+
+	if _, err = (&AttachCommandArgs{Replay: true, From: "2022-10-07"}).LogsFrom(now); err == nil {
+		t.Fail()
+	}
+
+	if _, err = (&AttachCommandArgs{From: "yesterday"}).LogsFrom(now); err == nil {
+		t.Fail()
+	}
+}
+
+func TestMustParseAttachCommand(t *testing.T) {
+	cmdArgs := &AttachCommandArgs{}
+	mustParseCommand("attach", cmdArgs, []string{"--replay", JobID})
+
+	if cmdArgs.JobID != JobID || !cmdArgs.Replay {
+		t.Fail()
+	}
+}
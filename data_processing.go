@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"path"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/ovh/go-ovh/ovh"
 )
 
 const DataProcessingSubmit = "/cloud/project/%s/dataProcessing/jobs"
+const DataProcessingList = "/cloud/project/%s/dataProcessing/jobs"
 const DataProcessingLog = "/cloud/project/%s/dataProcessing/jobs/%s/logs"
 const DataProcessingStatus = "/cloud/project/%s/dataProcessing/jobs/%s"
 
@@ -42,6 +46,11 @@ const ParameterArgs = "arguments"
 
 const ParameterPropertiesFile = "properties_file"
 
+const LogsFromLayout = "2006-01-02T15:04:05.000"
+
+// ListJobsParallelism number of job statuses fetched at once by ListJobs
+const ListJobsParallelism = 8
+
 const JobTypeJava = "java"
 const JobTypePython = "python"
 
@@ -96,10 +105,27 @@ type (
 		EngineParameters []*JobEngineParameter `json:"engineParameters"`
 	}
 
+	// JobFilter criteria of the jobs to list, zero values match every job
+	JobFilter struct {
+		Statuses      []string
+		Name          string
+		CreatedAfter  time.Time
+		CreatedBefore time.Time
+		EngineVersion string
+	}
+
 	Client struct {
-		OVH          *ovh.Client
-		lastPrintLog uint64
-		JobID        string
+		OVH       *ovh.Client
+		ProjectID string
+		JobID     string
+		// LogsFrom lower bound (formatted as LogsFromLayout) of the logs fetched by GetLogLast, all the logs when empty
+		LogsFrom string
+		// Retry policy of the failed API calls, no retry when nil
+		Retry *RetryPolicy
+		// LogsAddress address of the logs of the job in the object storage, once it's over
+		LogsAddress string
+		// logCursor position of the logs streamed by GetLogLast
+		logCursor *LogCursor
 	}
 )
 
@@ -108,7 +134,9 @@ func (c *Client) GetStatus(projectID string, jobID string) (*JobStatus, error) {
 
 	job := &JobStatus{}
 	path := fmt.Sprintf(DataProcessingStatus, url.QueryEscape(projectID), url.QueryEscape(jobID))
-	return job, c.OVH.Get(path, job)
+	return job, c.retry(func() error {
+		return c.OVH.Get(path, job)
+	})
 }
 
 // GetLog get log of the job from the API
@@ -118,29 +146,227 @@ func (c *Client) GetLog(projectID string, jobID string, from string) (*JobLog, e
 	if from != "" {
 		path = path + "?from=" + from
 	}
-	return jobLog, c.OVH.Get(path, jobLog)
+	return jobLog, c.retry(func() error {
+		return c.OVH.Get(path, jobLog)
+	})
 }
 
-// GetLog get log of the job from the API
+// GetLogLast get the logs of the job not returned by the previous calls, from LogsFrom on the first call.
+// The logs are sorted and each one is returned once, even when the pages of the API overlap
 func (c *Client) GetLogLast(projectID string, jobID string) (*JobLog, error) {
-	t := time.Unix(0, int64(c.lastPrintLog)).In(time.UTC)
-	from := t.Format("2006-01-02T15:04:05")
-	return c.GetLog(projectID, jobID, from+".000")
+	if c.logCursor == nil {
+		c.logCursor = NewLogCursor(c.LogsFrom)
+	}
+
+	jobLog, err := c.GetLog(projectID, jobID, c.logCursor.From())
+	if err != nil {
+		return nil, err
+	}
+	jobLog.Logs = c.logCursor.Next(jobLog.Logs)
+	return jobLog, nil
 }
 
-// Submit job to the API
+// Submit job to the API. Before retrying the submission, it checks that the job hasn't been created anyway,
+// to never submit it twice: a job with the same name and engine parameters created since the submission,
+// which isn't among the jobs of the project listed before the first attempt
 func (c *Client) Submit(projectID string, params *JobSubmit) (*JobStatus, error) {
 	log.Printf("Submitting job %s ...", params.Name)
 	job := &JobStatus{}
 
+	var knownJobIDs []string
+	if c.Retry != nil {
+		var err error
+		if knownJobIDs, err = c.List(projectID); err != nil {
+			log.Printf("Unable to list the jobs before the submission, a retry will check all of them: %s", err)
+		}
+	}
+
 	path := fmt.Sprintf(DataProcessingSubmit, url.QueryEscape(projectID))
-	return job, c.OVH.Post(path, params, job)
+	submitDate := time.Now()
+	attempt := 0
+	err := c.retry(func() error {
+		attempt++
+		if attempt > 1 {
+			submitted, err := c.findSubmitted(projectID, params, submitDate, knownJobIDs)
+			if err != nil {
+				return err
+			}
+			if submitted != nil {
+				log.Printf("Job %s has already been submitted with id %s", params.Name, submitted.ID)
+				job = submitted
+				return nil
+			}
+		}
+		return c.OVH.Post(path, params, job)
+	})
+	return job, err
+}
+
+// findSubmitted find the job submitted with the given parameters since the submit date among the jobs which aren't known,
+// nil if none. One minute of clock skew with the API is tolerated, and the jobs which can't be fetched are skipped
+func (c *Client) findSubmitted(projectID string, params *JobSubmit, submitDate time.Time, knownJobIDs []string) (*JobStatus, error) {
+	jobIDs, err := c.List(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &JobFilter{CreatedAfter: submitDate.Add(-time.Minute)}
+	for _, jobID := range jobIDs {
+		if inTheList(jobID, knownJobIDs) {
+			continue
+		}
+		job, err := c.GetStatus(projectID, jobID)
+		if err != nil {
+			log.Printf("Unable to retrieve status for job %s, skipped: %s", jobID, err)
+			continue
+		}
+		if job.Name == params.Name && filter.Match(job) && sameEngineParameters(job.EngineParameters, params.EngineParameters) {
+			return job, nil
+		}
+	}
+	return nil, nil
+}
+
+// sameEngineParameters test if both lists have the same engine parameters, in any order
+func sameEngineParameters(a, b []*JobEngineParameter) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	values := make(map[string]int, len(a))
+	for _, param := range a {
+		values[param.Name+"="+param.Value]++
+	}
+	for _, param := range b {
+		key := param.Name + "=" + param.Value
+		if values[key] == 0 {
+			return false
+		}
+		values[key]--
+	}
+	return true
+}
+
+// List get the IDs of the jobs of the project from the API
+func (c *Client) List(projectID string) ([]string, error) {
+	var jobIDs []string
+	path := fmt.Sprintf(DataProcessingList, url.QueryEscape(projectID))
+	err := c.retry(func() error {
+		return c.OVH.Get(path, &jobIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return jobIDs, nil
+}
+
+// ListJobs get the jobs of the project matching the filter, from the most recent to the oldest
+func (c *Client) ListJobs(projectID string, filter *JobFilter) ([]*JobStatus, error) {
+	jobIDs, err := c.List(projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the statuses are fetched by a few workers, the jobs which can't be fetched (eg. deleted since) are skipped
+	queue := make(chan string)
+	var mu sync.Mutex
+	jobs := make([]*JobStatus, 0, len(jobIDs))
+	var wg sync.WaitGroup
+	for i := 0; i < ListJobsParallelism; i++ {
+		wg.Add(1)
+		worker := c.clone()
+		go func() {
+			defer wg.Done()
+			for jobID := range queue {
+				job, err := worker.GetStatus(projectID, jobID)
+				if err != nil {
+					log.Printf("Unable to retrieve status for job %s, skipped: %s", jobID, err)
+					continue
+				}
+				if filter.Match(job) {
+					mu.Lock()
+					jobs = append(jobs, job)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, jobID := range jobIDs {
+		queue <- jobID
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if jobs[i].CreationDate != jobs[j].CreationDate {
+			return jobs[i].CreationDate > jobs[j].CreationDate
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs, nil
+}
+
+// clone copy the client with its own HTTP client, the OVH client setting its timeout on each call
+func (c *Client) clone() *Client {
+	ovhClient := *c.OVH
+	if ovhClient.Client != nil {
+		httpClient := *ovhClient.Client
+		ovhClient.Client = &httpClient
+	}
+	clone := *c
+	clone.OVH = &ovhClient
+	return &clone
+}
+
+// Match test if the job matches the filter
+func (f *JobFilter) Match(job *JobStatus) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.Statuses) > 0 && !inTheList(job.Status, f.Statuses) {
+		return false
+	}
+
+	if f.Name != "" {
+		if matched, err := path.Match(f.Name, job.Name); err != nil || !matched {
+			return false
+		}
+	}
+
+	if f.EngineVersion != "" && f.EngineVersion != job.EngineVersion {
+		return false
+	}
+
+	if !f.CreatedAfter.IsZero() || !f.CreatedBefore.IsZero() {
+		creationDate, err := time.Parse(time.RFC3339, job.CreationDate)
+		if err != nil {
+			return false
+		}
+		if !f.CreatedAfter.IsZero() && creationDate.Before(f.CreatedAfter) {
+			return false
+		}
+		if !f.CreatedBefore.IsZero() && creationDate.After(f.CreatedBefore) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // Kill job
 func (c *Client) Kill(projectID string, jobID string) error {
 	path := fmt.Sprintf(DataProcessingStatus, url.QueryEscape(projectID), url.QueryEscape(jobID))
-	return c.OVH.Delete(path, nil)
+	return c.retry(func() error {
+		return c.OVH.Delete(path, nil)
+	})
+}
+
+// retry call the API according to the retry policy of the client
+func (c *Client) retry(call func() error) error {
+	if c.Retry == nil {
+		return call()
+	}
+	return c.Retry.Do(call)
 }
 
 // GetErrorDetails return the error details as a formatted string
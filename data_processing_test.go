@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -338,3 +339,160 @@ func TestGetLastLog(t *testing.T) {
 	}
 
 }
+
+func TestList(t *testing.T) {
+	// Init test
+	var InputRequest *http.Request
+	ts, ovh := initMockServer(&InputRequest, 200, `["`+JobID+`"]`, nil, time.Duration(0))
+	defer ts.Close()
+
+	client := &Client{
+		OVH: ovh,
+	}
+
+	res, err := client.List(ProjectID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 1 || res[0] != JobID {
+		t.Fail()
+	}
+
+	if InputRequest.URL.Path != "/cloud/project/"+ProjectID+"/dataProcessing/jobs" {
+		t.Fail()
+	}
+}
+
+func TestListJobs(t *testing.T) {
+	jobs := map[string]*JobStatus{
+		"job-1": {ID: "job-1", Name: "etl-daily", Status: JobStatusCOMPLETED, EngineVersion: "3.3.0", CreationDate: "2022-10-06T09:00:00Z"},
+		"job-2": {ID: "job-2", Name: "etl-hourly", Status: JobStatusRUNNING, EngineVersion: "3.3.0", CreationDate: "2022-10-07T09:00:00Z"},
+		"job-3": {ID: "job-3", Name: "etl-hourly", Status: JobStatusRUNNING, EngineVersion: "2.4.3", CreationDate: "2022-10-07T10:00:00Z"},
+		"job-4": {ID: "job-4", Name: "adhoc", Status: JobStatusRUNNING, EngineVersion: "3.3.0", CreationDate: "2022-10-07T11:00:00Z"},
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RequestURI == "/auth/time" {
+			fmt.Fprint(w, MockTime)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jobsPath := fmt.Sprintf(DataProcessingList, ProjectID)
+		if r.URL.Path == jobsPath {
+			json.NewEncoder(w).Encode([]string{"job-1", "job-2", "job-3", "job-4", "job-5"})
+			return
+		}
+		job, ok := jobs[strings.TrimPrefix(r.URL.Path, jobsPath+"/")]
+		if !ok {
+			// deleted since the listing, skipped
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+			return
+		}
+		json.NewEncoder(w).Encode(job)
+	}))
+	defer ts.Close()
+
+	ovhClient, _ := ovh.NewClient(ts.URL, MockApplicationKey, MockApplicationSecret, MockConsumerKey)
+	client := &Client{
+		OVH: ovhClient,
+	}
+
+	res, err := client.ListJobs(ProjectID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 4 || res[0].ID != "job-4" || res[3].ID != "job-1" {
+		t.Fail()
+	}
+
+	res, err = client.ListJobs(ProjectID, &JobFilter{
+		Statuses:      []string{JobStatusRUNNING},
+		Name:          "etl-*",
+		EngineVersion: "3.3.0",
+		CreatedAfter:  time.Date(2022, 10, 7, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].ID != "job-2" {
+		t.Fail()
+	}
+}
+
+func TestJobFilterMatch(t *testing.T) {
+	job := &JobStatus{Name: "etl-daily", Status: JobStatusFAILED, EngineVersion: "3.3.0", CreationDate: "2022-10-07T09:01:09.693Z"}
+
+	if !(&JobFilter{}).Match(job) {
+		t.Fail()
+	}
+
+	if (&JobFilter{Statuses: []string{JobStatusCOMPLETED}}).Match(job) {
+		t.Fail()
+	}
+
+	if (&JobFilter{Name: "adhoc-*"}).Match(job) {
+		t.Fail()
+	}
+
+	if (&JobFilter{CreatedBefore: time.Date(2022, 10, 7, 9, 0, 0, 0, time.UTC)}).Match(job) {
+		t.Fail()
+	}
+
+	if !(&JobFilter{CreatedAfter: time.Date(2022, 10, 7, 9, 0, 0, 0, time.UTC)}).Match(job) {
+		t.Fail()
+	}
+}
+
+func TestGetLastLogCursor(t *testing.T) {
+	jobLog := `{"logs":[{"id":2,"content":"second","timestamp":"2019-12-03T09:40:15.500Z"},{"id":1,"content":"first","timestamp":"2019-12-03T09:40:15.500Z"}]}`
+	var InputRequest *http.Request
+	ts, ovh := initMockServer(&InputRequest, 200, jobLog, nil, time.Duration(0))
+	defer ts.Close()
+
+	client := &Client{
+		OVH: ovh,
+	}
+
+	res, err := client.GetLogLast(ProjectID, JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Logs) != 2 || res.Logs[0].Content != "first" || res.Logs[1].Content != "second" {
+		t.Errorf("unexpected logs %+v", res.Logs)
+	}
+
+	// the next page overlaps the previous one, its logs are only returned once
+	res, err = client.GetLogLast(ProjectID, JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Logs) != 0 {
+		t.Errorf("logs %+v returned twice", res.Logs)
+	}
+	if InputRequest.URL.Query().Get("from") != "2019-12-03T09:40:10.500" {
+		t.Errorf("unexpected from %s", InputRequest.URL.Query().Get("from"))
+	}
+}
+
+func TestGetLastLogFrom(t *testing.T) {
+	// Init test
+	var InputRequest *http.Request
+	ts, ovh := initMockServer(&InputRequest, 200, `{"logs":[]}`, nil, time.Duration(0))
+	defer ts.Close()
+
+	client := &Client{
+		OVH:      ovh,
+		LogsFrom: "2022-10-07T09:00:00.000",
+	}
+
+	if _, err := client.GetLogLast(ProjectID, JobID); err != nil {
+		t.Fatal(err)
+	}
+
+	if InputRequest.URL.Query().Get("from") != client.LogsFrom {
+		t.Fail()
+	}
+}
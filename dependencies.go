@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// PyFilesExtensions extensions of the files accepted by --py-files
+	PyFilesExtensions = []string{".py", ".zip", ".egg"}
+	// JarsExtensions extensions of the files accepted by --jars
+	JarsExtensions = []string{".jar"}
+)
+
+// Dependencies resolve a comma-delimited list of dependencies of the job. Each dependency is either a local file,
+// to upload in the container of the job under the prefix, or a file already in it (protocol://container/path/to/file).
+// It returns the local files and the object names of all the dependencies in the container.
+// No extension is checked when extensions is empty
+func Dependencies(list string, container string, prefix string, extensions []string) (local []string, objects []string, err error) {
+	for _, dependency := range strings.Split(list, ",") {
+		dependency = strings.TrimSpace(dependency)
+		if dependency == "" {
+			continue
+		}
+
+		if len(extensions) > 0 && !inTheList(strings.ToLower(filepath.Ext(dependency)), extensions) {
+			return nil, nil, fmt.Errorf("%s must be a %s file", dependency, strings.Join(extensions, ", "))
+		}
+
+		if strings.Contains(dependency, "://") {
+			_, depContainer, object, err := ParseFilePath(dependency)
+			if err != nil {
+				return nil, nil, err
+			}
+			if depContainer != container {
+				return nil, nil, fmt.Errorf("%s must be in the container of the job %s", dependency, container)
+			}
+			objects = append(objects, object)
+			continue
+		}
+
+		info, err := os.Stat(dependency)
+		if err != nil {
+			return nil, nil, err
+		}
+		if info.IsDir() {
+			return nil, nil, fmt.Errorf("%s is a directory", dependency)
+		}
+		local = append(local, dependency)
+		objects = append(objects, path.Join(prefix, filepath.Base(dependency)))
+	}
+	return local, objects, nil
+}
+
+// LocalDependencies local files of a comma-delimited list of dependencies
+func LocalDependencies(list string) []string {
+	var local []string
+	for _, dependency := range splitList(list) {
+		if !strings.Contains(dependency, "://") {
+			local = append(local, dependency)
+		}
+	}
+	return local
+}
+
+// addUploads add the files to the comma-delimited list of files to upload, skipping the ones already in it
+func (a *CLIArgs) addUploads(files []string) {
+	uploads := splitList(a.Upload)
+	for _, file := range files {
+		if !inTheList(file, uploads) {
+			uploads = append(uploads, file)
+		}
+	}
+	a.Upload = strings.Join(uploads, ",")
+}
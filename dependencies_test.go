@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	local, objects, err := Dependencies("testdata/py2.py, swift://odp/libs/helpers.zip", "odp", "", PyFilesExtensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(local) != 1 || local[0] != "testdata/py2.py" {
+		t.Errorf("unexpected local dependencies %q", local)
+	}
+
+	if len(objects) != 2 || objects[0] != "py2.py" || objects[1] != "libs/helpers.zip" {
+		t.Errorf("unexpected dependencies %q", objects)
+	}
+}
+
+func TestDependenciesPrefix(t *testing.T) {
+	_, objects, err := Dependencies("testdata/py2.py,swift://odp/libs/helpers.zip", "odp", "jobs/team-a", PyFilesExtensions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the local dependencies are uploaded under the prefix
+	if len(objects) != 2 || objects[0] != "jobs/team-a/py2.py" || objects[1] != "libs/helpers.zip" {
+		t.Errorf("unexpected dependencies %q", objects)
+	}
+}
+
+func TestDependenciesErr(t *testing.T) {
+	for _, list := range []string{"testdata/jar.jar", "testdata/missing.py", "swift://other/helpers.zip", "testdata"} {
+		if _, _, err := Dependencies(list, "odp", "", PyFilesExtensions); err == nil {
+			t.Errorf("%s must be invalid", list)
+		}
+	}
+}
+
+func TestAddUploads(t *testing.T) {
+	a := &CLIArgs{Upload: "testdata/py.py"}
+	a.addUploads([]string{"testdata/py.py", "testdata/py2.py"})
+
+	if a.Upload != "testdata/py.py,testdata/py2.py" {
+		t.Errorf("unexpected uploads %s", a.Upload)
+	}
+
+	// the list is trimmed as the files are uploaded
+	a = &CLIArgs{Upload: "testdata/py.py, testdata/py2.py,"}
+	a.addUploads([]string{"testdata/py2.py"})
+	if a.Upload != "testdata/py.py,testdata/py2.py" {
+		t.Errorf("unexpected uploads %s", a.Upload)
+	}
+
+	a = &CLIArgs{}
+	a.addUploads([]string{"testdata/jar.jar"})
+	if a.Upload != "testdata/jar.jar" {
+		t.Errorf("unexpected uploads %s", a.Upload)
+	}
+}
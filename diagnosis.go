@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	CauseMemoryLimit    = "memory_limit"
+	CauseOutOfMemory    = "out_of_memory"
+	CauseClassNotFound  = "class_not_found"
+	CauseMissingPackage = "missing_package"
+	CausePythonError    = "python_error"
+
+	// maxLogLineSize size of the longest log line scanned in the downloaded logs
+	maxLogLineSize = 1 << 20
+)
+
+// failureSignatures patterns of the log lines revealing the cause of a failure,
+// the first non empty group being the detail of the cause
+var failureSignatures = []struct {
+	cause   string
+	pattern *regexp.Regexp
+}{
+	{CauseMemoryLimit, regexp.MustCompile(`Container killed by YARN for exceeding (?:physical |virtual )?memory limits|exceeding memory limits|OOMKilled`)},
+	{CauseOutOfMemory, regexp.MustCompile(`java\.lang\.OutOfMemoryError(?::\s*(.+))?`)},
+	{CauseClassNotFound, regexp.MustCompile(`(?:ClassNotFoundException|NoClassDefFoundError):?\s+([\w.$/]+)`)},
+	{CauseMissingPackage, regexp.MustCompile(`unresolved dependency:\s+(\S+)|module not found:\s+(\S+)|::\s+(\S+#\S+)\s*:\s+not found`)},
+}
+
+var (
+	pythonTracebackPattern = regexp.MustCompile(`^Traceback \(most recent call last\):`)
+	pythonExceptionPattern = regexp.MustCompile(`^([A-Za-z_][\w.]*(?:Error|Exception|Exit|Interrupt))(?::\s*(.*))?$`)
+)
+
+type (
+	// Diagnosis detection of the causes of a job failure in its log lines
+	Diagnosis struct {
+		findings []*Finding
+		// traceback a python traceback is being read
+		traceback bool
+	}
+
+	// Finding cause of the failure of a job found in its logs
+	Finding struct {
+		Cause string `json:"cause"`
+		// Detail of the cause, such as the missing class
+		Detail string `json:"detail,omitempty"`
+		// Line log line revealing the cause
+		Line string `json:"line"`
+		// Hint how to fix the job
+		Hint string `json:"hint,omitempty"`
+	}
+)
+
+// Scan look for the failure causes in a log line
+func (d *Diagnosis) Scan(line string) {
+	if d.traceback {
+		trimmed := strings.TrimSpace(line)
+		if match := pythonExceptionPattern.FindStringSubmatch(trimmed); match != nil {
+			d.traceback = false
+			d.add(CausePythonError, match[1], trimmed)
+			return
+		}
+		// the traceback lines are indented, the exception ends it
+		if trimmed != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "\t") {
+			d.traceback = false
+		}
+	}
+	if pythonTracebackPattern.MatchString(strings.TrimSpace(line)) {
+		d.traceback = true
+		return
+	}
+
+	for _, signature := range failureSignatures {
+		match := signature.pattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		detail := ""
+		for _, group := range match[1:] {
+			if group != "" {
+				detail = strings.TrimRight(strings.TrimSpace(group), ":")
+				break
+			}
+		}
+		d.add(signature.cause, detail, strings.TrimSpace(line))
+		return
+	}
+}
+
+// add a finding, unless it has already been found
+func (d *Diagnosis) add(cause, detail, line string) {
+	for _, finding := range d.findings {
+		if finding.Cause == cause && finding.Detail == detail {
+			return
+		}
+	}
+	d.findings = append(d.findings, &Finding{Cause: cause, Detail: detail, Line: line})
+}
+
+// ScanFile look for the failure causes in a log file, decompressing it when it's gzip compressed
+func (d *Diagnosis) ScanFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		d.Scan(scanner.Text())
+	}
+	d.traceback = false
+	return scanner.Err()
+}
+
+// Findings causes of the failure of the job found, with the hints tied to its flags
+func (d *Diagnosis) Findings(job *JobStatus) []*Finding {
+	mainClass := ""
+	for _, parameter := range job.EngineParameters {
+		if parameter.Name == ParameterMainClassName {
+			mainClass = parameter.Value
+		}
+	}
+
+	findings := make([]*Finding, 0, len(d.findings))
+	for _, finding := range d.findings {
+		hinted := *finding
+		hinted.Hint = failureHint(finding, mainClass)
+		findings = append(findings, &hinted)
+	}
+	return findings
+}
+
+// failureHint how to fix the job failing with the finding
+func failureHint(finding *Finding, mainClass string) string {
+	switch finding.Cause {
+	case CauseMemoryLimit:
+		return "the containers were killed for exceeding their memory limits, increase --executor-memoryOverhead " +
+			"(off-heap memory such as the python workers) or --driver-memoryOverhead when it's the driver"
+	case CauseOutOfMemory:
+		return "the JVM ran out of memory, increase --executor-memory, or --driver-memory when it's the driver " +
+			"(eg. collect), or spread the data on more --num-executors"
+	case CauseClassNotFound:
+		if mainClass != "" && strings.ReplaceAll(finding.Detail, "/", ".") == mainClass {
+			return fmt.Sprintf("the main class %s of --class isn't in the jar of the job, check --class and the jar", mainClass)
+		}
+		return fmt.Sprintf("the class %s isn't on the classpath, add its jar with --jars or its Maven coordinates with --packages", finding.Detail)
+	case CauseMissingPackage:
+		return fmt.Sprintf("the package %s can't be resolved, check its Maven coordinates in --packages and its repository in --repositories", finding.Detail)
+	case CausePythonError:
+		if finding.Detail == "ModuleNotFoundError" || finding.Detail == "ImportError" {
+			return "a python module is missing, add it with --py-files or to the requirements.txt of --py-package"
+		}
+		return fmt.Sprintf("the python code raised %s, see its traceback in the logs", finding.Detail)
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// diagnose scan the log lines and return the findings for the job
+func diagnose(job *JobStatus, lines ...string) []*Finding {
+	d := &Diagnosis{}
+	for _, line := range lines {
+		d.Scan(line)
+	}
+	return d.Findings(job)
+}
+
+func TestDiagnosisMemory(t *testing.T) {
+	findings := diagnose(&JobStatus{ID: JobID, Status: JobStatusFAILED},
+		"22/10/07 09:40:15 INFO DAGScheduler: Job 0 started",
+		"22/10/07 09:41:15 WARN TaskSetManager: Lost task 1.0: ExecutorLostFailure (executor 1 exited caused by one of the running tasks) Reason: Container killed by YARN for exceeding memory limits. 5.5 GB of 5.5 GB physical memory used. Consider boosting spark.yarn.executor.memoryOverhead.",
+		"22/10/07 09:41:16 WARN TaskSetManager: Lost task 2.0: Container killed by YARN for exceeding memory limits.",
+		"java.lang.OutOfMemoryError: Java heap space",
+	)
+
+	if len(findings) != 2 {
+		t.Fatalf("unexpected findings %+v", findings)
+	}
+	if findings[0].Cause != CauseMemoryLimit || !strings.Contains(findings[0].Hint, "--executor-memoryOverhead") {
+		t.Errorf("unexpected finding %+v", findings[0])
+	}
+	if findings[1].Cause != CauseOutOfMemory || findings[1].Detail != "Java heap space" || !strings.Contains(findings[1].Hint, "--executor-memory") {
+		t.Errorf("unexpected finding %+v", findings[1])
+	}
+}
+
+func TestDiagnosisClassNotFound(t *testing.T) {
+	job := &JobStatus{
+		ID:               JobID,
+		Status:           JobStatusFAILED,
+		EngineParameters: []*JobEngineParameter{{Name: ParameterMainClassName, Value: "org.apache.spark.examples.SparkPi"}},
+	}
+
+	findings := diagnose(job,
+		"Error: Failed to load class org.apache.spark.examples.SparkPi.",
+		"java.lang.ClassNotFoundException: org.apache.spark.examples.SparkPi",
+		"Caused by: java.lang.NoClassDefFoundError: com/fasterxml/jackson/databind/ObjectMapper",
+	)
+	if len(findings) != 2 {
+		t.Fatalf("unexpected findings %+v", findings)
+	}
+	if findings[0].Detail != "org.apache.spark.examples.SparkPi" || !strings.Contains(findings[0].Hint, "--class") {
+		t.Errorf("unexpected finding %+v", findings[0])
+	}
+	if findings[1].Detail != "com/fasterxml/jackson/databind/ObjectMapper" || !strings.Contains(findings[1].Hint, "--jars") {
+		t.Errorf("unexpected finding %+v", findings[1])
+	}
+}
+
+func TestDiagnosisMissingPackage(t *testing.T) {
+	findings := diagnose(&JobStatus{ID: JobID, Status: JobStatusFAILED},
+		"\t\t::::::::::::::::::::::::::::::::::::::::::::::",
+		"\t\t::          UNRESOLVED DEPENDENCIES         ::",
+		"\t\t:: com.example#missing_2.12;1.0.0: not found",
+		"Exception in thread \"main\" java.lang.RuntimeException: [unresolved dependency: com.example#missing_2.12;1.0.0: not found]",
+	)
+	if len(findings) != 1 || findings[0].Cause != CauseMissingPackage || findings[0].Detail != "com.example#missing_2.12;1.0.0" ||
+		!strings.Contains(findings[0].Hint, "--packages") {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+}
+
+func TestDiagnosisPythonTraceback(t *testing.T) {
+	findings := diagnose(&JobStatus{ID: JobID, Status: JobStatusFAILED},
+		"Traceback (most recent call last):",
+		"  File \"/opt/spark/work-dir/main.py\", line 3, in <module>",
+		"    import helpers",
+		"ModuleNotFoundError: No module named 'helpers'",
+		"22/10/07 09:41:15 INFO ShutdownHookManager: Shutdown hook called",
+		"Traceback (most recent call last):",
+		"  File \"/opt/spark/work-dir/main.py\", line 8, in <module>",
+		"ZeroDivisionError: division by zero",
+	)
+	if len(findings) != 2 {
+		t.Fatalf("unexpected findings %+v", findings)
+	}
+	if findings[0].Detail != "ModuleNotFoundError" || !strings.Contains(findings[0].Hint, "--py-files") ||
+		findings[0].Line != "ModuleNotFoundError: No module named 'helpers'" {
+		t.Errorf("unexpected finding %+v", findings[0])
+	}
+	if findings[1].Detail != "ZeroDivisionError" {
+		t.Errorf("unexpected finding %+v", findings[1])
+	}
+
+	// an exception line outside of a traceback isn't a python error
+	if findings := diagnose(&JobStatus{ID: JobID}, "ValueError: invalid literal"); len(findings) != 0 {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+}
+
+func TestDiagnosisScanFile(t *testing.T) {
+	dir := t.TempDir()
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	gz.Write([]byte("22/10/07 09:40:15 INFO Executor: Running task\njava.lang.OutOfMemoryError: GC overhead limit exceeded\n"))
+	gz.Close()
+	if err := os.WriteFile(filepath.Join(dir, "executor-1.log.gz"), b.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Diagnosis{}
+	if err := d.ScanFile(filepath.Join(dir, "executor-1.log.gz")); err != nil {
+		t.Fatal(err)
+	}
+	findings := d.Findings(&JobStatus{ID: JobID, Status: JobStatusFAILED})
+	if len(findings) != 1 || findings[0].Detail != "GC overhead limit exceeded" {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+}
+
+func TestOutputDiagnosis(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewOutput(OutputJSON, &buf)
+	o.SetLogFilter(&LogFilter{MinLevel: LogLevelFatal})
+
+	// the filtered out lines are diagnosed too
+	o.Log(JobID, &Log{ID: 1, Content: "22/10/07 09:41:15 ERROR Executor: java.lang.OutOfMemoryError: Java heap space"})
+	o.Diagnosis(&JobStatus{ID: JobID, Status: JobStatusFAILED}, nil)
+
+	events := readEvents(t, &buf)
+	if len(events) != 1 || events[0].Type != EventDiagnosis || len(events[0].Findings) != 1 ||
+		events[0].Findings[0].Cause != CauseOutOfMemory || events[0].Findings[0].Hint == "" {
+		t.Errorf("unexpected events %q", buf.String())
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// Exit codes of the CLI. A job COMPLETED exits with the return code of the spark application as is, which can
+// be one of these codes, the other codes tell why the job failed or couldn't be submitted
+const (
+	// ExitCodeError unexpected error
+	ExitCodeError = 1
+	// ExitCodeConfig invalid configuration.ini or job configuration
+	ExitCodeConfig = 64
+	// ExitCodeUpload upload of the files to the object storage failed
+	ExitCodeUpload = 65
+	// ExitCodeSubmitRejected job rejected by the API (eg. 422 when it exceeds the Data Processing capabilities)
+	ExitCodeSubmitRejected = 66
+	// ExitCodeAPI unable to call the API (network, authentication, server errors...)
+	ExitCodeAPI = 67
+	// ExitCodeJobFailed job ended with the FAILED status
+	ExitCodeJobFailed = 68
+	// ExitCodeJobTerminated job TERMINATED, by its TTL or killed outside of this CLI
+	ExitCodeJobTerminated = 69
+	// ExitCodeKilled job killed by this CLI on a signal
+	ExitCodeKilled = 143
+	// ExitCodeUsage invalid command line arguments, as exited by arg.Parser.Fail
+	ExitCodeUsage = 255
+)
+
+// ExitCode exit code of the CLI once the job ended
+func ExitCode(job *JobStatus) int {
+	switch job.Status {
+	case JobStatusCOMPLETED:
+		return int(job.ReturnCode)
+	case JobStatusFAILED:
+		return ExitCodeJobFailed
+	case JobStatusCANCELLING, JobStatusTERMINATED:
+		return ExitCodeJobTerminated
+	default:
+		return ExitCodeError
+	}
+}
+
+// Fatalf log the error and exit with the given code
+func Fatalf(code int, format string, v ...interface{}) {
+	log.Printf(format, v...)
+	os.Exit(code)
+}
@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestExitCode(t *testing.T) {
+	for _, test := range []struct {
+		job      *JobStatus
+		exitCode int
+	}{
+		{&JobStatus{Status: JobStatusCOMPLETED, ReturnCode: 0}, 0},
+		{&JobStatus{Status: JobStatusCOMPLETED, ReturnCode: 3}, 3},
+		{&JobStatus{Status: JobStatusFAILED, ReturnCode: 0}, ExitCodeJobFailed},
+		{&JobStatus{Status: JobStatusFAILED, ReturnCode: 1}, ExitCodeJobFailed},
+		{&JobStatus{Status: JobStatusTERMINATED, ReturnCode: 0}, ExitCodeJobTerminated},
+		{&JobStatus{Status: JobStatusCANCELLING, ReturnCode: 0}, ExitCodeJobTerminated},
+		{&JobStatus{Status: JobStatusUNKNOWN, ReturnCode: 0}, ExitCodeError},
+	} {
+		if exitCode := ExitCode(test.job); exitCode != test.exitCode {
+			t.Errorf("exit code of %s job with return code %d is %d instead of %d", test.job.Status, test.job.ReturnCode, exitCode, test.exitCode)
+		}
+	}
+}
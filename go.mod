@@ -5,23 +5,29 @@ go 1.18
 require (
 	github.com/Pallinder/go-randomdata v1.2.0
 	github.com/alexflint/go-arg v1.3.0
+	github.com/aws/aws-sdk-go v1.44.256
 	github.com/dustin/go-humanize v1.0.0
 	github.com/gabriel-vasile/mimetype v1.1.0
 	github.com/hjson/hjson-go/v4 v4.2.0
 	github.com/imdario/mergo v0.3.13
+	github.com/johannesboyne/gofakes3 v0.0.0-20230506070712-04da935ef877
 	github.com/ncw/swift v1.0.52
 	github.com/ovh/go-ovh v1.1.1-0.20211209132054-5bcee91ddcd5
 	github.com/peterhellberg/duration v0.0.0-20191119133758-ec6baeebcd10
+	golang.org/x/term v0.7.0
 	gopkg.in/ini.v1 v1.57.0
 )
 
 require (
 	github.com/alexflint/go-scalar v1.0.0 // indirect
+	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/modocache/gover v0.0.0-20171022184752-b58185e213c5 // indirect
+	github.com/ryszard/goskiplist v0.0.0-20150312221310-2dfbae5fcf46 // indirect
+	github.com/shabbyrobe/gocovmerge v0.0.0-20190829150210-3e036491d500 // indirect
 	github.com/stretchr/testify v1.5.1 // indirect
-	golang.org/x/sys v0.1.0 // indirect
-	golang.org/x/text v0.4.0 // indirect
-	golang.org/x/tools v0.2.0 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/text v0.9.0 // indirect
+	golang.org/x/tools v0.8.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
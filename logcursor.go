@@ -0,0 +1,192 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LogsOverlap duration before the last log of the job from which the logs are fetched again,
+// to get the logs which arrived late, the ones already returned being skipped. The logs arriving later than
+// that fall before the requested pages: they are lost, and counted in LogCursor.Lost from the gaps of the log IDs
+const LogsOverlap = 5 * time.Second
+
+// logsTimestampLayouts layouts of the log timestamps without time zone, which are in UTC
+var logsTimestampLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"}
+
+// LogCursor position of the streaming of the logs of a job. Each page of logs is fetched from the timestamp
+// of the last returned log minus LogsOverlap, the logs already returned are skipped by ID and the new ones are
+// sorted by timestamp then ID, so that no log is duplicated nor lost within the overlap whatever the order of the pages
+type LogCursor struct {
+	// Timestamp of the most recent log returned
+	Timestamp time.Time
+	// ID of the most recent log returned
+	ID uint64
+	// Lost number of the logs never returned, missing from the IDs of the returned ones once the pages are past them
+	Lost int
+	// from lower bound of the logs, none when zero
+	from time.Time
+	// window lower bound of the last page requested, older logs have been returned or skipped before
+	window time.Time
+	// seen logs returned by ID with their timestamp, zero for the ones without valid timestamp
+	seen map[uint64]time.Time
+	// maxID highest ID of the logs returned, valid once a log has been returned
+	maxID    uint64
+	returned bool
+	// gaps ranges of IDs missing from the logs returned, which can still arrive
+	gaps []*logGap
+}
+
+// logGap range of consecutive log IDs missing before a returned log. The IDs of the logs increase as they arrive,
+// so the missing ones aren't more recent than the log after them: they are lost once the pages are past its timestamp
+type logGap struct {
+	first     uint64
+	last      uint64
+	missing   int
+	timestamp time.Time
+}
+
+// NewLogCursor create a cursor returning the logs from the given date (formatted as LogsFromLayout), all when empty
+func NewLogCursor(from string) *LogCursor {
+	cursor := &LogCursor{seen: map[uint64]time.Time{}}
+	if from != "" {
+		cursor.from, _ = ParseLogTimestamp(from)
+	}
+	return cursor
+}
+
+// ParseLogTimestamp parse the timestamp of a log, with or without fractional seconds and time zone
+func ParseLogTimestamp(timestamp string) (time.Time, error) {
+	var err error
+	for _, layout := range logsTimestampLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, strings.TrimSpace(timestamp)); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// From lower bound (formatted as LogsFromLayout) of the next page of logs to request, empty for all the logs
+func (c *LogCursor) From() string {
+	from := c.from
+	if !c.Timestamp.IsZero() {
+		if overlap := c.Timestamp.Add(-LogsOverlap); overlap.After(from) {
+			from = overlap
+		}
+	}
+	// the requested bound is truncated to the milliseconds, as formatted
+	c.window = from.Truncate(time.Millisecond)
+	if from.IsZero() {
+		return ""
+	}
+	return c.window.Format(LogsFromLayout)
+}
+
+// Next return the logs of the page not returned yet, sorted by timestamp then ID, and move the cursor after them
+func (c *LogCursor) Next(logs []*Log) []*Log {
+	type timedLog struct {
+		log       *Log
+		timestamp time.Time
+	}
+
+	var next []*timedLog
+	for _, l := range logs {
+		if _, ok := c.seen[l.ID]; ok {
+			continue
+		}
+
+		timestamp, err := ParseLogTimestamp(l.Timestamp)
+		if err == nil && timestamp.Before(c.from) {
+			continue
+		}
+		if err == nil && timestamp.Before(c.window) {
+			// older than the requested page, it has already been returned
+			continue
+		}
+		// a log appearing twice in the page is returned once
+		c.seen[l.ID] = timestamp
+		next = append(next, &timedLog{log: l, timestamp: timestamp})
+	}
+
+	// the gaps of the IDs are tracked in the order of arrival
+	sort.SliceStable(next, func(i, j int) bool {
+		return next[i].log.ID < next[j].log.ID
+	})
+	for _, l := range next {
+		c.track(l.log.ID, l.timestamp)
+	}
+	c.reportLost()
+
+	sort.SliceStable(next, func(i, j int) bool {
+		if !next[i].timestamp.Equal(next[j].timestamp) {
+			return next[i].timestamp.Before(next[j].timestamp)
+		}
+		return next[i].log.ID < next[j].log.ID
+	})
+
+	result := make([]*Log, 0, len(next))
+	for _, l := range next {
+		result = append(result, l.log)
+		if !l.timestamp.Before(c.Timestamp) {
+			c.Timestamp = l.timestamp
+			c.ID = l.log.ID
+		}
+	}
+	c.prune()
+	return result
+}
+
+// track fill the gap of a log which arrived late, or open a gap before a log which isn't the next one
+func (c *LogCursor) track(id uint64, timestamp time.Time) {
+	if !c.returned || id > c.maxID {
+		if c.returned && id > c.maxID+1 {
+			if timestamp.IsZero() {
+				timestamp = c.Timestamp
+			}
+			c.gaps = append(c.gaps, &logGap{first: c.maxID + 1, last: id - 1, missing: int(id - c.maxID - 1), timestamp: timestamp})
+		}
+		c.maxID = id
+		c.returned = true
+		return
+	}
+
+	for _, gap := range c.gaps {
+		if id >= gap.first && id <= gap.last {
+			gap.missing--
+			return
+		}
+	}
+}
+
+// reportLost count and log the missing logs which can't arrive in the next pages anymore
+func (c *LogCursor) reportLost() {
+	lost := 0
+	gaps := c.gaps[:0]
+	for _, gap := range c.gaps {
+		switch {
+		case gap.missing == 0:
+		case !gap.timestamp.IsZero() && gap.timestamp.Before(c.window):
+			lost += gap.missing
+		default:
+			gaps = append(gaps, gap)
+		}
+	}
+	c.gaps = gaps
+
+	if lost > 0 {
+		c.Lost += lost
+		log.Printf("%d log line(s) lost, they arrived more than %s after the more recent ones", lost, LogsOverlap)
+	}
+}
+
+// prune forget the logs older than the next page, they can't be returned anymore
+func (c *LogCursor) prune() {
+	bound := c.Timestamp.Add(-LogsOverlap).Truncate(time.Millisecond)
+	for id, timestamp := range c.seen {
+		if !timestamp.IsZero() && timestamp.Before(bound) {
+			delete(c.seen, id)
+		}
+	}
+}
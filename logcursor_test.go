@@ -0,0 +1,192 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// logIDs list the IDs of the logs
+func logIDs(logs []*Log) []uint64 {
+	ids := make([]uint64, 0, len(logs))
+	for _, l := range logs {
+		ids = append(ids, l.ID)
+	}
+	return ids
+}
+
+func equalIDs(ids []uint64, expected ...uint64) bool {
+	if len(ids) != len(expected) {
+		return false
+	}
+	for i := range ids {
+		if ids[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseLogTimestamp(t *testing.T) {
+	expected := time.Date(2019, 12, 3, 9, 40, 15, 0, time.UTC)
+	tests := map[string]time.Time{
+		"2019-12-03T09:40:15Z":          expected,
+		"2019-12-03T09:40:15.250Z":      expected.Add(250 * time.Millisecond),
+		"2019-12-03T09:40:15.250":       expected.Add(250 * time.Millisecond),
+		"2019-12-03T10:40:15.25+01:00":  expected.Add(250 * time.Millisecond),
+		"2019-12-03T09:40:15.123456789": expected.Add(123456789),
+	}
+	for timestamp, expected := range tests {
+		if parsed, err := ParseLogTimestamp(timestamp); err != nil || !parsed.Equal(expected) {
+			t.Errorf("%s parsed as %s: %v", timestamp, parsed, err)
+		}
+	}
+
+	if _, err := ParseLogTimestamp("yesterday"); err == nil {
+		t.Fail()
+	}
+}
+
+func TestLogCursorFrom(t *testing.T) {
+	cursor := NewLogCursor("")
+	if from := cursor.From(); from != "" {
+		t.Errorf("all the logs must be requested first, not from %s", from)
+	}
+
+	cursor.Next([]*Log{{ID: 1, Timestamp: "2019-12-03T09:40:15.250Z"}})
+	if from := cursor.From(); from != "2019-12-03T09:40:10.250" {
+		t.Errorf("unexpected from %s", from)
+	}
+
+	cursor = NewLogCursor("2019-12-03T09:40:12.000")
+	if from := cursor.From(); from != "2019-12-03T09:40:12.000" {
+		t.Errorf("unexpected from %s", from)
+	}
+	// the overlap doesn't go before the lower bound
+	cursor.Next([]*Log{{ID: 1, Timestamp: "2019-12-03T09:40:15.250Z"}})
+	if from := cursor.From(); from != "2019-12-03T09:40:12.000" {
+		t.Errorf("unexpected from %s", from)
+	}
+}
+
+func TestLogCursorOverlappingPages(t *testing.T) {
+	cursor := NewLogCursor("")
+	cursor.From()
+
+	page := []*Log{
+		{ID: 1, Timestamp: "2019-12-03T09:40:15.100Z"},
+		{ID: 2, Timestamp: "2019-12-03T09:40:15.100Z"},
+	}
+	if ids := logIDs(cursor.Next(page)); !equalIDs(ids, 1, 2) {
+		t.Errorf("unexpected logs %v", ids)
+	}
+
+	// the next page starts before the last log, with new logs of the same millisecond
+	cursor.From()
+	page = []*Log{
+		{ID: 1, Timestamp: "2019-12-03T09:40:15.100Z"},
+		{ID: 2, Timestamp: "2019-12-03T09:40:15.100Z"},
+		{ID: 3, Timestamp: "2019-12-03T09:40:15.100Z"},
+		{ID: 4, Timestamp: "2019-12-03T09:40:16Z"},
+	}
+	if ids := logIDs(cursor.Next(page)); !equalIDs(ids, 3, 4) {
+		t.Errorf("unexpected logs %v", ids)
+	}
+
+	// same page again
+	cursor.From()
+	if ids := logIDs(cursor.Next(page)); len(ids) != 0 {
+		t.Errorf("logs %v returned twice", ids)
+	}
+	if cursor.ID != 4 {
+		t.Errorf("unexpected last log %d", cursor.ID)
+	}
+}
+
+func TestLogCursorOutOfOrder(t *testing.T) {
+	cursor := NewLogCursor("")
+	cursor.From()
+
+	page := []*Log{
+		{ID: 3, Timestamp: "2019-12-03T09:40:17Z"},
+		{ID: 1, Timestamp: "2019-12-03T09:40:15Z"},
+		{ID: 3, Timestamp: "2019-12-03T09:40:17Z"},
+		{ID: 2, Timestamp: "2019-12-03T09:40:17Z"},
+	}
+	if ids := logIDs(cursor.Next(page)); !equalIDs(ids, 1, 2, 3) {
+		t.Errorf("unexpected logs %v", ids)
+	}
+
+	// a log which arrived late, within the overlap, isn't lost
+	cursor.From()
+	page = []*Log{
+		{ID: 5, Timestamp: "2019-12-03T09:40:16Z"},
+		{ID: 3, Timestamp: "2019-12-03T09:40:17Z"},
+		{ID: 4, Timestamp: "2019-12-03T09:40:18Z"},
+	}
+	if ids := logIDs(cursor.Next(page)); !equalIDs(ids, 5, 4) {
+		t.Errorf("unexpected logs %v", ids)
+	}
+	if !cursor.Timestamp.Equal(time.Date(2019, 12, 3, 9, 40, 18, 0, time.UTC)) || cursor.ID != 4 {
+		t.Errorf("unexpected cursor %s %d", cursor.Timestamp, cursor.ID)
+	}
+
+	// a log older than the requested page has already been returned
+	cursor.From()
+	if ids := logIDs(cursor.Next([]*Log{{ID: 1, Timestamp: "2019-12-03T09:40:15Z"}})); len(ids) != 0 {
+		t.Errorf("logs %v returned twice", ids)
+	}
+	if cursor.Lost != 0 {
+		t.Errorf("no log is lost: %d", cursor.Lost)
+	}
+}
+
+func TestLogCursorLost(t *testing.T) {
+	cursor := NewLogCursor("")
+	cursor.From()
+	cursor.Next([]*Log{{ID: 1, Timestamp: "2019-12-03T09:40:15Z"}, {ID: 2, Timestamp: "2019-12-03T09:40:16Z"}})
+
+	// the logs 3 to 5 haven't arrived yet
+	cursor.From()
+	if ids := logIDs(cursor.Next([]*Log{{ID: 6, Timestamp: "2019-12-03T09:40:17Z"}})); !equalIDs(ids, 6) || cursor.Lost != 0 {
+		t.Errorf("unexpected logs %v, %d lost", ids, cursor.Lost)
+	}
+
+	// the log 3 arrives within the overlap
+	cursor.From()
+	if ids := logIDs(cursor.Next([]*Log{{ID: 3, Timestamp: "2019-12-03T09:40:16.500Z"}, {ID: 7, Timestamp: "2019-12-03T09:40:20Z"}})); !equalIDs(ids, 3, 7) || cursor.Lost != 0 {
+		t.Errorf("unexpected logs %v, %d lost", ids, cursor.Lost)
+	}
+
+	// once the pages are past the log 6, the logs 4 and 5 can't arrive anymore
+	cursor.From()
+	if ids := logIDs(cursor.Next([]*Log{{ID: 8, Timestamp: "2019-12-03T09:40:30Z"}})); !equalIDs(ids, 8) || cursor.Lost != 0 {
+		t.Errorf("unexpected logs %v, %d lost", ids, cursor.Lost)
+	}
+	cursor.From()
+	if ids := logIDs(cursor.Next(nil)); len(ids) != 0 || cursor.Lost != 2 {
+		t.Errorf("unexpected logs %v, %d lost", ids, cursor.Lost)
+	}
+
+	// counted once
+	cursor.From()
+	cursor.Next(nil)
+	if cursor.Lost != 2 {
+		t.Errorf("%d lost", cursor.Lost)
+	}
+}
+
+func TestLogCursorInvalidTimestamp(t *testing.T) {
+	cursor := NewLogCursor("")
+	cursor.From()
+
+	page := []*Log{
+		{ID: 2, Timestamp: "2019-12-03T09:40:15Z"},
+		{ID: 1, Timestamp: ""},
+	}
+	if ids := logIDs(cursor.Next(page)); !equalIDs(ids, 1, 2) {
+		t.Errorf("unexpected logs %v", ids)
+	}
+	if ids := logIDs(cursor.Next(page)); len(ids) != 0 {
+		t.Errorf("logs %v returned twice", ids)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// DefaultLogFileMaxSize size in MiB from which the log file is rotated
+	DefaultLogFileMaxSize = 100
+	// DefaultLogFileMaxFiles number of rotated log files kept
+	DefaultLogFileMaxFiles = 5
+	// DefaultLogTail number of the last log lines printed when the job doesn't complete
+	DefaultLogTail = 50
+)
+
+// LogFile local file the log lines of the job are written to. Once it exceeds MaxSize it's rotated:
+// it's renamed <path>.1 (compressed as <path>.1.gz with Compress), the previous <path>.1 becomes <path>.2, and so on
+// until MaxFiles, the oldest one being deleted
+type LogFile struct {
+	Path     string
+	MaxSize  int64
+	MaxFiles int
+	Compress bool
+	// TailSize number of the last lines kept for Tail
+	TailSize int
+
+	f    *os.File
+	size int64
+	tail []string
+	next int
+}
+
+// NewLogFile create the log file from the values of the flags, the default values being used for the ones not set (nil).
+// The max size is in MiB, 0 disabling the rotation, and 0 max files or tail size keeps no rotated file or no tail
+func NewLogFile(path string, maxSize *int, maxFiles *int, compress bool, tailSize *int) *LogFile {
+	return &LogFile{
+		Path:     path,
+		MaxSize:  int64(logFileSetting(maxSize, DefaultLogFileMaxSize)) << 20,
+		MaxFiles: logFileSetting(maxFiles, DefaultLogFileMaxFiles),
+		Compress: compress,
+		TailSize: logFileSetting(tailSize, DefaultLogTail),
+	}
+}
+
+// logFileSetting value of a flag of the log file, the default value when it isn't set
+func logFileSetting(value *int, defaultValue int) int {
+	if value == nil {
+		return defaultValue
+	}
+	return *value
+}
+
+// ValidLogFileSettings test if the values of the flags of the log file set are positive
+func ValidLogFileSettings(values ...*int) bool {
+	for _, value := range values {
+		if value != nil && *value < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Open the log file, the lines being appended to the existing ones
+func (l *LogFile) Open() error {
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		if closeErr := f.Close(); closeErr != nil {
+			return fmt.Errorf("%w, closing the file failed too: %s", err, closeErr)
+		}
+		return err
+	}
+	l.f = f
+	l.size = info.Size()
+	return nil
+}
+
+// WriteLine write a log line, rotating the file first when it would exceed its max size
+func (l *LogFile) WriteLine(line string) error {
+	l.keep(line)
+
+	length := int64(len(line) + 1)
+	if l.MaxSize > 0 && l.size > 0 && l.size+length > l.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := fmt.Fprintln(l.f, line)
+	l.size += int64(n)
+	return err
+}
+
+// keep the line in the tail
+func (l *LogFile) keep(line string) {
+	if l.TailSize <= 0 {
+		return
+	}
+	if len(l.tail) < l.TailSize {
+		l.tail = append(l.tail, line)
+		return
+	}
+	l.tail[l.next] = line
+	l.next = (l.next + 1) % l.TailSize
+}
+
+// Tail last lines written, from the oldest one
+func (l *LogFile) Tail() []string {
+	tail := make([]string, 0, len(l.tail))
+	tail = append(tail, l.tail[l.next:]...)
+	return append(tail, l.tail[:l.next]...)
+}
+
+// rotatedPath path of the nth rotated file
+func (l *LogFile) rotatedPath(n int) string {
+	path := fmt.Sprintf("%s.%d", l.Path, n)
+	if l.Compress {
+		path += ".gz"
+	}
+	return path
+}
+
+// rotate shift the rotated files, move the current file to the first one and reopen an empty file
+func (l *LogFile) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+
+	if l.MaxFiles <= 0 {
+		if err := os.Remove(l.Path); err != nil {
+			return err
+		}
+		return l.Open()
+	}
+
+	if err := os.Remove(l.rotatedPath(l.MaxFiles)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := l.MaxFiles - 1; n > 0; n-- {
+		if err := os.Rename(l.rotatedPath(n), l.rotatedPath(n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if l.Compress {
+		if err := gzipFile(l.Path, l.rotatedPath(1)); err != nil {
+			return err
+		}
+		if err := os.Remove(l.Path); err != nil {
+			return err
+		}
+	} else if err := os.Rename(l.Path, l.rotatedPath(1)); err != nil {
+		return err
+	}
+	return l.Open()
+}
+
+// gzipFile compress the source file into dest
+func gzipFile(source, dest string) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	if _, err := io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Close the log file
+func (l *LogFile) Close() error {
+	return l.f.Close()
+}
+
+// PrintTail print the last lines written, with a header
+func PrintTail(w io.Writer, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "==> last %d log lines <==\n%s\n", len(lines), strings.Join(lines, "\n"))
+}
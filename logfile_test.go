@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewLogFile(t *testing.T) {
+	l := NewLogFile("job.log", nil, nil, false, nil)
+	if l.MaxSize != DefaultLogFileMaxSize<<20 || l.MaxFiles != DefaultLogFileMaxFiles || l.TailSize != DefaultLogTail {
+		t.Errorf("unexpected defaults %+v", l)
+	}
+
+	maxSize, maxFiles, tail := 10, 2, 20
+	l = NewLogFile("job.log", &maxSize, &maxFiles, true, &tail)
+	if l.MaxSize != 10<<20 || l.MaxFiles != 2 || !l.Compress || l.TailSize != 20 {
+		t.Errorf("unexpected log file %+v", l)
+	}
+
+	// 0 isn't the default
+	zero := 0
+	l = NewLogFile("job.log", &zero, &zero, false, &zero)
+	if l.MaxSize != 0 || l.MaxFiles != 0 || l.TailSize != 0 {
+		t.Errorf("unexpected log file %+v", l)
+	}
+
+	if negative := -1; ValidLogFileSettings(&maxSize, nil, &negative) || !ValidLogFileSettings(&zero, nil) {
+		t.Error("only the positive values are valid")
+	}
+}
+
+func TestLogFileRotateNoFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.log")
+	l := &LogFile{Path: path, MaxSize: 20}
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := l.WriteLine(fmt.Sprintf("line %04d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	if written, err := os.ReadFile(path); err != nil || string(written) != "line 0002\n" {
+		t.Errorf("unexpected content %q: %v", written, err)
+	}
+	if _, err := os.Stat(path + ".1"); !os.IsNotExist(err) {
+		t.Error("no rotated file must be kept")
+	}
+}
+
+func TestLogFileRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.log")
+	// 10 bytes per line, 3 lines per file
+	l := &LogFile{Path: path, MaxSize: 30, MaxFiles: 2, TailSize: 4}
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if err := l.WriteLine(fmt.Sprintf("line %04d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		path:        "line 0009\n",
+		path + ".1": "line 0006\nline 0007\nline 0008\n",
+		path + ".2": "line 0003\nline 0004\nline 0005\n",
+	}
+	for file, content := range expected {
+		if written, err := os.ReadFile(file); err != nil || string(written) != content {
+			t.Errorf("unexpected content %q of %s: %v", written, file, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("the oldest log file must be deleted")
+	}
+
+	if tail := strings.Join(l.Tail(), ","); tail != "line 0006,line 0007,line 0008,line 0009" {
+		t.Errorf("unexpected tail %s", tail)
+	}
+}
+
+func TestLogFileRotateCompress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "job.log")
+	if err := os.WriteFile(path, []byte("line 0000\nline 0001\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the lines are appended to the existing file
+	l := &LogFile{Path: path, MaxSize: 30, MaxFiles: 1, Compress: true}
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"line 0002", "line 0003"} {
+		if err := l.WriteLine(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l.Close()
+
+	f, err := os.Open(path + ".1.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := io.ReadAll(gz)
+	if err != nil || string(rotated) != "line 0000\nline 0001\nline 0002\n" {
+		t.Errorf("unexpected rotated content %q: %v", rotated, err)
+	}
+
+	if written, err := os.ReadFile(path); err != nil || string(written) != "line 0003\n" {
+		t.Errorf("unexpected content %q: %v", written, err)
+	}
+	if len(l.Tail()) != 0 {
+		t.Error("no tail must be kept without tail size")
+	}
+}
+
+func TestOutputLogFile(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewOutput(OutputText, &buf)
+	filter, _ := NewLogFilter(ConsoleLogLevel("", "job.log"), "", false)
+	o.SetLogFilter(filter)
+
+	path := filepath.Join(t.TempDir(), "job.log")
+	tail := 2
+	l := NewLogFile(path, nil, nil, false, &tail)
+	if err := l.Open(); err != nil {
+		t.Fatal(err)
+	}
+	o.SetLogFile(l)
+
+	lines := []string{
+		"22/10/07 09:40:15 INFO SparkContext: Running Spark version 3.2.1",
+		"22/10/07 09:40:16 ERROR SparkContext: Error initializing SparkContext.",
+		"java.lang.ClassNotFoundException: org.apache.spark.examples.SparkPi",
+	}
+	for i, line := range lines {
+		o.Log(JobID, &Log{ID: uint64(i), Content: line})
+	}
+
+	// only the errors are printed to the console
+	if buf.String() != strings.Join(lines[1:], "\n")+"\n" {
+		t.Errorf("unexpected console output %q", buf.String())
+	}
+
+	buf.Reset()
+	o.CloseLogFile(&JobStatus{ID: JobID, Status: JobStatusFAILED})
+	if buf.String() != "==> last 2 log lines <==\n"+strings.Join(lines[1:], "\n")+"\n" {
+		t.Errorf("unexpected tail %q", buf.String())
+	}
+
+	if written, err := os.ReadFile(path); err != nil || string(written) != strings.Join(lines, "\n")+"\n" {
+		t.Errorf("unexpected log file %q: %v", written, err)
+	}
+
+	// closed once
+	o.CloseLogFile(&JobStatus{ID: JobID, Status: JobStatusFAILED})
+}
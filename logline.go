@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	LogLevelTrace = "TRACE"
+	LogLevelDebug = "DEBUG"
+	LogLevelInfo  = "INFO"
+	LogLevelWarn  = "WARN"
+	LogLevelError = "ERROR"
+	LogLevelFatal = "FATAL"
+
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	colorFaint  = "\033[2m"
+)
+
+// LogLevels levels of the log lines, from the least to the most severe
+var LogLevels = []string{LogLevelTrace, LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError, LogLevelFatal}
+
+// logLevelAliases levels of the python logging named differently
+var logLevelAliases = map[string]string{
+	"WARNING":  LogLevelWarn,
+	"CRITICAL": LogLevelFatal,
+}
+
+// logLevelColors color of the levels printed in a terminal
+var logLevelColors = map[string]string{
+	LogLevelTrace: colorFaint,
+	LogLevelDebug: colorFaint,
+	LogLevelWarn:  colorYellow,
+	LogLevelError: colorRed,
+	LogLevelFatal: colorRed,
+}
+
+const logLevelPattern = `(?P<level>TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL|CRITICAL)`
+
+// logLinePatterns formats of the spark log lines
+var logLinePatterns = []*regexp.Regexp{
+	// log4j: "22/10/07 09:40:15 INFO SparkContext: Running Spark version 3.2.1"
+	// or "2022-10-07 09:40:15,123 WARN org.apache.spark.SparkConf: ..."
+	regexp.MustCompile(`^\d[\d/\-]*[ T][\d:.,]+Z?\s+` + logLevelPattern + `\s+(?:\[[^\]]*\]\s+)?(?P<logger>[^\s:]+):\s?(?P<message>.*)$`),
+	// python logging default format: "ERROR:root:message"
+	regexp.MustCompile(`^` + logLevelPattern + `:(?P<logger>[^:\s]*):(?P<message>.*)$`),
+	// python logging "%(asctime)s - %(name)s - %(levelname)s - %(message)s" format
+	regexp.MustCompile(`^\d[\d\-]*[ T][\d:.,]+\s+-\s+(?P<logger>\S+)\s+-\s+` + logLevelPattern + `\s+-\s+(?P<message>.*)$`),
+}
+
+type (
+	// LogLine log line of a job parsed
+	LogLine struct {
+		// Level normalized level of the line, empty when the line hasn't any (eg. stack traces or prints)
+		Level   string
+		Logger  string
+		Message string
+		// levelStart and levelEnd position of the level in the line
+		levelStart int
+		levelEnd   int
+	}
+
+	// LogFilter filter and highlighting of the streamed log lines. The lines without level, such as the stack
+	// traces, have the level of the previous line, but the start of the python tracebacks and the exception
+	// lines which are ERROR ones
+	LogFilter struct {
+		// MinLevel least severe level of the printed lines, all when empty
+		MinLevel string
+		// Grep regular expression the printed lines must match, all when nil
+		Grep *regexp.Regexp
+		// Color highlight the levels with ANSI colors
+		Color bool
+		// lastLevel level of the previous line
+		lastLevel string
+	}
+)
+
+// ParseLogLine parse a log line of one of the common spark formats, the message is the whole line otherwise
+func ParseLogLine(content string) *LogLine {
+	for _, pattern := range logLinePatterns {
+		match := pattern.FindStringSubmatchIndex(content)
+		if match == nil {
+			continue
+		}
+
+		line := &LogLine{}
+		for i, name := range pattern.SubexpNames() {
+			if name == "" || match[2*i] < 0 {
+				continue
+			}
+			value := content[match[2*i]:match[2*i+1]]
+			switch name {
+			case "level":
+				line.Level = NormalizeLogLevel(value)
+				line.levelStart, line.levelEnd = match[2*i], match[2*i+1]
+			case "logger":
+				line.Logger = value
+			case "message":
+				line.Message = value
+			}
+		}
+		return line
+	}
+	return &LogLine{Message: content}
+}
+
+// NormalizeLogLevel convert a level to one of LogLevels, empty when it isn't a level
+func NormalizeLogLevel(level string) string {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	if alias, ok := logLevelAliases[level]; ok {
+		return alias
+	}
+	if inTheList(level, LogLevels) {
+		return level
+	}
+	return ""
+}
+
+// logLevelIndex severity of the level, -1 when it isn't a level
+func logLevelIndex(level string) int {
+	for i, l := range LogLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewLogFilter create the filter of the log lines from the --log-level and --grep values, nil when none is set
+func NewLogFilter(level string, grep string, color bool) (*LogFilter, error) {
+	filter := &LogFilter{Color: color}
+	if level != "" {
+		filter.MinLevel = NormalizeLogLevel(level)
+		if filter.MinLevel == "" {
+			return nil, fmt.Errorf("invalid value for --log-level. It must be one of %s", strings.Join(LogLevels, ", "))
+		}
+	}
+
+	if grep != "" {
+		var err error
+		if filter.Grep, err = regexp.Compile(grep); err != nil {
+			return nil, fmt.Errorf("invalid value for --grep: %s", err)
+		}
+	}
+
+	if filter.MinLevel == "" && filter.Grep == nil && !color {
+		return nil, nil
+	}
+	return filter, nil
+}
+
+// ConsoleLogLevel least severe level of the log lines printed to the console: the --log-level one,
+// or ERROR when the logs are written to a file
+func ConsoleLogLevel(level string, logFile string) string {
+	if level == "" && logFile != "" {
+		return LogLevelError
+	}
+	return level
+}
+
+// Match parse the log line and test if it must be printed
+func (f *LogFilter) Match(content string) (*LogLine, bool) {
+	line := ParseLogLine(content)
+	if f == nil {
+		return line, true
+	}
+
+	level := line.Level
+	if level == "" {
+		level = f.lastLevel
+		// a python traceback printed after INFO lines is an error
+		trimmed := strings.TrimSpace(content)
+		if pythonTracebackPattern.MatchString(trimmed) || pythonExceptionPattern.MatchString(trimmed) {
+			level = LogLevelError
+		}
+	}
+	f.lastLevel = level
+
+	if f.MinLevel != "" && level != "" && logLevelIndex(level) < logLevelIndex(f.MinLevel) {
+		return line, false
+	}
+	if f.Grep != nil && !f.Grep.MatchString(content) {
+		return line, false
+	}
+	return line, true
+}
+
+// Highlight color the level of the log line when enabled
+func (f *LogFilter) Highlight(content string, line *LogLine) string {
+	if f == nil || !f.Color || line.Level == "" {
+		return content
+	}
+	color, ok := logLevelColors[line.Level]
+	if !ok {
+		return content
+	}
+	return content[:line.levelStart] + color + content[line.levelStart:line.levelEnd] + colorReset + content[line.levelEnd:]
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseLogLine(t *testing.T) {
+	tests := []struct {
+		content string
+		level   string
+		logger  string
+		message string
+	}{
+		{"22/10/07 09:40:15 INFO SparkContext: Running Spark version 3.2.1", LogLevelInfo, "SparkContext", "Running Spark version 3.2.1"},
+		{"2022-10-07 09:40:15,123 WARN org.apache.spark.SparkConf: The configuration key is deprecated", LogLevelWarn, "org.apache.spark.SparkConf", "The configuration key is deprecated"},
+		{"2022-10-07T09:40:15.123Z ERROR [main] org.apache.spark.SparkContext: Error initializing SparkContext.", LogLevelError, "org.apache.spark.SparkContext", "Error initializing SparkContext."},
+		{"ERROR:root:Unable to read the input", LogLevelError, "root", "Unable to read the input"},
+		{"WARNING:py4j.java_gateway:Answer received", LogLevelWarn, "py4j.java_gateway", "Answer received"},
+		{"2022-10-07 09:40:15,123 - etl - CRITICAL - Stopping", LogLevelFatal, "etl", "Stopping"},
+		{"Traceback (most recent call last):", "", "", "Traceback (most recent call last):"},
+		{"Pi is roughly 3.14", "", "", "Pi is roughly 3.14"},
+	}
+	for _, test := range tests {
+		line := ParseLogLine(test.content)
+		if line.Level != test.level || line.Logger != test.logger || line.Message != test.message {
+			t.Errorf("%q parsed as %+v", test.content, line)
+		}
+	}
+}
+
+func TestNewLogFilter(t *testing.T) {
+	if filter, err := NewLogFilter("", "", false); err != nil || filter != nil {
+		t.Errorf("unexpected filter %+v: %v", filter, err)
+	}
+
+	filter, err := NewLogFilter("warning", "Exception", false)
+	if err != nil || filter.MinLevel != LogLevelWarn || filter.Grep == nil {
+		t.Errorf("unexpected filter %+v: %v", filter, err)
+	}
+
+	if _, err := NewLogFilter("VERBOSE", "", false); err == nil {
+		t.Error("invalid level must be rejected")
+	}
+	if _, err := NewLogFilter("", "(", false); err == nil {
+		t.Error("invalid regular expression must be rejected")
+	}
+}
+
+func TestLogFilterMatch(t *testing.T) {
+	filter, _ := NewLogFilter(LogLevelWarn, "", false)
+	lines := []struct {
+		content string
+		printed bool
+	}{
+		{"Pi is roughly 3.14", true},
+		{"22/10/07 09:40:15 INFO SparkContext: Running Spark version 3.2.1", false},
+		{"22/10/07 09:40:16 ERROR SparkContext: Error initializing SparkContext.", true},
+		// the stack trace has the level of the error
+		{"java.lang.ClassNotFoundException: org.apache.spark.examples.SparkPi", true},
+		{"22/10/07 09:40:17 INFO ShutdownHookManager: Shutdown hook called", false},
+		{"\tat java.base/java.lang.Thread.run(Thread.java:829)", false},
+	}
+	for _, line := range lines {
+		if _, ok := filter.Match(line.content); ok != line.printed {
+			t.Errorf("%q matched %t", line.content, ok)
+		}
+	}
+
+	// the python tracebacks are errors, whatever the level of the previous lines
+	filter, _ = NewLogFilter(LogLevelError, "", false)
+	lines = []struct {
+		content string
+		printed bool
+	}{
+		{"22/10/07 09:40:15 INFO SparkContext: Running Spark version 3.2.1", false},
+		{"Traceback (most recent call last):", true},
+		{"  File \"/opt/spark/work-dir/main.py\", line 8, in <module>", true},
+		{"ZeroDivisionError: division by zero", true},
+		{"22/10/07 09:40:17 INFO ShutdownHookManager: Shutdown hook called", false},
+		{"ValueError: invalid literal for int() with base 10: 'x'", true},
+	}
+	for _, line := range lines {
+		if _, ok := filter.Match(line.content); ok != line.printed {
+			t.Errorf("%q matched %t", line.content, ok)
+		}
+	}
+
+	filter, _ = NewLogFilter("", "(?i)exception", false)
+	if _, ok := filter.Match("java.lang.ClassNotFoundException: org.apache.spark.examples.SparkPi"); !ok {
+		t.Fail()
+	}
+	if _, ok := filter.Match("22/10/07 09:40:16 ERROR SparkContext: Error initializing SparkContext."); ok {
+		t.Fail()
+	}
+}
+
+func TestLogFilterHighlight(t *testing.T) {
+	content := "22/10/07 09:40:16 ERROR SparkContext: Error initializing SparkContext."
+	filter, _ := NewLogFilter("", "", true)
+
+	line, _ := filter.Match(content)
+	expected := "22/10/07 09:40:16 " + colorRed + "ERROR" + colorReset + " SparkContext: Error initializing SparkContext."
+	if highlighted := filter.Highlight(content, line); highlighted != expected {
+		t.Errorf("unexpected highlighted line %q", highlighted)
+	}
+
+	line, _ = filter.Match("22/10/07 09:40:15 INFO SparkContext: Running Spark version 3.2.1")
+	if filter.Highlight("22/10/07 09:40:15 INFO SparkContext: Running Spark version 3.2.1", line) != "22/10/07 09:40:15 INFO SparkContext: Running Spark version 3.2.1" {
+		t.Error("info level must not be colored")
+	}
+}
+
+func TestOutputLogFilter(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewOutput(OutputJSON, &buf)
+	filter, _ := NewLogFilter(LogLevelError, "", false)
+	o.SetLogFilter(filter)
+
+	o.Log(JobID, &Log{ID: 1, Content: "22/10/07 09:40:15 INFO SparkContext: Running Spark version 3.2.1"})
+	o.Log(JobID, &Log{ID: 2, Content: "22/10/07 09:40:16 ERROR SparkContext: Error initializing SparkContext."})
+
+	events := readEvents(t, &buf)
+	if len(events) != 1 || events[0].Log.ID != 2 || events[0].Level != LogLevelError {
+		t.Errorf("unexpected events %q", buf.String())
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	ini "gopkg.in/ini.v1"
+
+	"data-processing-spark-submit/upload"
+)
+
+// LogsDownload download of the logs of a job from the object storage
+type LogsDownload struct {
+	Storage upload.StorageI
+	// Dir local directory of the downloaded logs
+	Dir string
+	// Decompress the gzip compressed logs
+	Decompress bool
+	// Concat concatenate the downloaded logs in <job id>.log
+	Concat bool
+}
+
+// ParseLogsAddress split the address of the logs of a job
+// (https://storage.gra.cloud.ovh.net/v1/AUTH_xxx/container?prefix=jobid) into its container and prefix
+func ParseLogsAddress(logsAddress string) (container, prefix string, err error) {
+	u, err := url.Parse(logsAddress)
+	if err != nil {
+		return "", "", err
+	}
+
+	// skip the version and the account of the path
+	segments := strings.SplitN(strings.Trim(u.Path, "/"), "/", 4)
+	if len(segments) < 3 || segments[2] == "" {
+		return "", "", fmt.Errorf("%s isn't a valid logs address", logsAddress)
+	}
+
+	container = segments[2]
+	prefix = u.Query().Get("prefix")
+	if prefix == "" && len(segments) == 4 {
+		prefix = segments[3]
+	}
+	return container, prefix, nil
+}
+
+// LogsStorage storage used to download the logs: swift, in which the logs are, or s3 when it's the only one configured
+func LogsStorage(conf map[string]*ini.Section, protocols []string) (upload.StorageI, error) {
+	for _, protocol := range []string{SwiftConfig, S3Config} {
+		if inTheList(protocol, protocols) {
+			return upload.New(conf[protocol], protocol)
+		}
+	}
+	return nil, fmt.Errorf("no storage configured to download the logs")
+}
+
+// Run download the logs of the ended job and return the local files, errors are only logged as the job is already over
+func (d *LogsDownload) Run(client *Client, job *JobStatus) []string {
+	if d == nil {
+		return nil
+	}
+
+	logsAddress := client.LogsAddress
+	if logsAddress == "" {
+		jobLog, err := client.GetLog(client.ProjectID, job.ID, "")
+		if err != nil {
+			log.Printf("Unable to download the logs: %s", err)
+			return nil
+		}
+		logsAddress = jobLog.LogsAddress
+	}
+	if logsAddress == "" {
+		log.Printf("Unable to download the logs: no logs address for job %s", job.ID)
+		return nil
+	}
+
+	files, err := d.Download(job.ID, logsAddress)
+	if err != nil {
+		log.Printf("Unable to download the logs: %s", err)
+		return files
+	}
+	log.Printf("%d log file(s) of job %s downloaded in %s", len(files), job.ID, d.Dir)
+	return files
+}
+
+// Download download the logs at the logs address, it returns the local files
+func (d *LogsDownload) Download(jobID, logsAddress string) ([]string, error) {
+	container, prefix, err := ParseLogsAddress(logsAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := d.Storage.List(container, prefix)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+
+	var files []string
+	for _, object := range objects {
+		rel := strings.TrimPrefix(strings.TrimPrefix(object.Name, prefix), "/")
+		if rel == "" {
+			rel = path.Base(object.Name)
+		}
+		file := filepath.Join(d.Dir, filepath.FromSlash(path.Clean("/"+rel)))
+		if d.Decompress {
+			file = strings.TrimSuffix(file, ".gz")
+		}
+
+		if err := d.download(container, object.Name, file); err != nil {
+			return files, fmt.Errorf("unable to download %s: %w", object.Name, err)
+		}
+		files = append(files, file)
+	}
+
+	if d.Concat && len(files) > 0 {
+		file := filepath.Join(d.Dir, jobID+".log")
+		if err := ConcatLogs(files, file); err != nil {
+			return files, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// download the object in the file, decompressing it if needed
+func (d *LogsDownload) download(container, object, file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if !d.Decompress || !strings.HasSuffix(object, ".gz") {
+		if err := d.Storage.Download(container, object, f); err != nil {
+			return err
+		}
+		return f.Close()
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(d.Storage.Download(container, object, w))
+	}()
+	defer r.Close()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, gz); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// ConcatLogs concatenate the log files in dest, each one preceded by its name,
+// the gzip compressed files being decompressed
+func ConcatLogs(files []string, dest string) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, file := range files {
+		if _, err := fmt.Fprintf(f, "==> %s <==\n", filepath.Base(file)); err != nil {
+			return err
+		}
+
+		if err := concatLog(f, file); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(f); err != nil {
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// concatLog copy the content of the log file, decompressing it when it's gzip compressed
+func concatLog(w io.Writer, file string) error {
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	var r io.Reader = src
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
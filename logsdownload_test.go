@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"data-processing-spark-submit/upload"
+)
+
+// mockLogsStorage storage holding the content of the objects of a container
+type mockLogsStorage struct {
+	upload.StorageI
+	objects map[string]string
+}
+
+func (s *mockLogsStorage) List(dest, prefix string) ([]*upload.Object, error) {
+	var objects []*upload.Object
+	for name := range s.objects {
+		if strings.HasPrefix(name, prefix) {
+			objects = append(objects, &upload.Object{Name: name})
+		}
+	}
+	return objects, nil
+}
+
+func (s *mockLogsStorage) Download(dest, object string, w io.Writer) error {
+	content, ok := s.objects[object]
+	if !ok {
+		return fmt.Errorf("%s not found", object)
+	}
+	_, err := io.WriteString(w, content)
+	return err
+}
+
+func gzipString(t *testing.T, content string) string {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	if _, err := gz.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestParseLogsAddress(t *testing.T) {
+	tests := []struct {
+		address   string
+		container string
+		prefix    string
+	}{
+		{"https://storage.gra.cloud.ovh.net/v1/AUTH_xxx/odp-logs?prefix=" + JobID, "odp-logs", JobID},
+		{"https://storage.gra.cloud.ovh.net/v1/AUTH_xxx/odp-logs/" + JobID, "odp-logs", JobID},
+		{"https://storage.gra.cloud.ovh.net/v1/AUTH_xxx/odp-logs", "odp-logs", ""},
+	}
+	for _, test := range tests {
+		container, prefix, err := ParseLogsAddress(test.address)
+		if err != nil || container != test.container || prefix != test.prefix {
+			t.Errorf("%s: unexpected container %q and prefix %q: %v", test.address, container, prefix, err)
+		}
+	}
+
+	if _, _, err := ParseLogsAddress("https://storage.gra.cloud.ovh.net/v1/AUTH_xxx"); err == nil {
+		t.Error("an address without container must be rejected")
+	}
+}
+
+func TestLogsDownload(t *testing.T) {
+	storage := &mockLogsStorage{objects: map[string]string{
+		JobID + "/driver.log.gz":     gzipString(t, "driver output"),
+		JobID + "/executor-1.log.gz": gzipString(t, "executor output"),
+		JobID + "/../escape.log":     "outside",
+		"other-job/driver.log":       "other",
+	}}
+	dir := t.TempDir()
+	d := &LogsDownload{Storage: storage, Dir: dir, Decompress: true, Concat: true}
+
+	files, err := d.Download(JobID, "https://storage.gra.cloud.ovh.net/v1/AUTH_xxx/odp-logs?prefix="+JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "escape.log"),
+		filepath.Join(dir, "driver.log"),
+		filepath.Join(dir, "executor-1.log"),
+		filepath.Join(dir, JobID+".log"),
+	}
+	if len(files) != len(expected) {
+		t.Fatalf("unexpected files %q", files)
+	}
+	for i := range expected {
+		if files[i] != expected[i] {
+			t.Errorf("unexpected file %s instead of %s", files[i], expected[i])
+		}
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "driver.log"))
+	if err != nil || string(content) != "driver output" {
+		t.Errorf("unexpected driver log %q: %v", content, err)
+	}
+
+	content, err = os.ReadFile(filepath.Join(dir, JobID+".log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "==> driver.log <==\ndriver output\n") ||
+		!strings.Contains(string(content), "==> executor-1.log <==\nexecutor output\n") {
+		t.Errorf("unexpected concatenated logs %q", content)
+	}
+}
+
+func TestLogsDownloadCompressed(t *testing.T) {
+	compressed := gzipString(t, "driver output")
+	storage := &mockLogsStorage{objects: map[string]string{JobID + "/driver.log.gz": compressed}}
+	dir := t.TempDir()
+	d := &LogsDownload{Storage: storage, Dir: dir}
+
+	files, err := d.Download(JobID, "https://storage.gra.cloud.ovh.net/v1/AUTH_xxx/odp-logs?prefix="+JobID)
+	if err != nil || len(files) != 1 || files[0] != filepath.Join(dir, "driver.log.gz") {
+		t.Fatalf("unexpected files %q: %v", files, err)
+	}
+
+	content, err := os.ReadFile(files[0])
+	if err != nil || string(content) != compressed {
+		t.Errorf("the logs must be kept compressed: %v", err)
+	}
+}
+
+func TestLogsDownloadConcatCompressed(t *testing.T) {
+	storage := &mockLogsStorage{objects: map[string]string{
+		JobID + "/driver.log.gz": gzipString(t, "driver output"),
+		JobID + "/stdout.log":    "stdout output",
+	}}
+	dir := t.TempDir()
+	d := &LogsDownload{Storage: storage, Dir: dir, Concat: true}
+
+	files, err := d.Download(JobID, "https://storage.gra.cloud.ovh.net/v1/AUTH_xxx/odp-logs?prefix="+JobID)
+	if err != nil || len(files) != 3 {
+		t.Fatalf("unexpected files %q: %v", files, err)
+	}
+
+	// the downloaded logs are kept compressed, but not the concatenated ones
+	content, err := os.ReadFile(filepath.Join(dir, JobID+".log"))
+	if err != nil || string(content) != "==> driver.log.gz <==\ndriver output\n==> stdout.log <==\nstdout output\n" {
+		t.Errorf("unexpected concatenated logs %q: %v", content, err)
+	}
+}
+
+func TestLogsDownloadInvalidGzip(t *testing.T) {
+	storage := &mockLogsStorage{objects: map[string]string{JobID + "/driver.log.gz": "not compressed"}}
+	d := &LogsDownload{Storage: storage, Dir: t.TempDir(), Decompress: true}
+
+	if _, err := d.Download(JobID, "https://storage.gra.cloud.ovh.net/v1/AUTH_xxx/odp-logs?prefix="+JobID); err == nil {
+		t.Error("invalid gzip logs must fail")
+	}
+}
+
+func TestLogsDownloadRunNil(t *testing.T) {
+	var d *LogsDownload
+	d.Run(&Client{}, &JobStatus{ID: JobID, Status: JobStatusCOMPLETED})
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"data-processing-spark-submit/utils"
+)
+
+const (
+	EventSubmitted      = "submitted"
+	EventStatus         = "status"
+	EventLog            = "log"
+	EventJob            = "job"
+	EventLogsAddress    = "logs_address"
+	EventKilled         = "killed"
+	EventDiagnosis      = "diagnosis"
+	EventLogsDownloaded = "logs_downloaded"
+)
+
+type (
+	// Event line of the json output
+	Event struct {
+		Type        string     `json:"type"`
+		Time        string     `json:"time"`
+		JobID       string     `json:"jobId,omitempty"`
+		JobName     string     `json:"jobName,omitempty"`
+		Status      string     `json:"status,omitempty"`
+		Log         *Log       `json:"log,omitempty"`
+		Level       string     `json:"level,omitempty"`
+		Job         *JobStatus `json:"job,omitempty"`
+		LogsAddress string     `json:"logsAddress,omitempty"`
+		Findings    []*Finding `json:"findings,omitempty"`
+		Files       []string   `json:"files,omitempty"`
+	}
+
+	// Output print the CLI output either as human readable text or as newline-delimited json events
+	Output struct {
+		Format     string
+		w          io.Writer
+		mu         sync.Mutex
+		lastStatus string
+		// logFilter filter and highlighting of the log lines, all printed as is when nil
+		logFilter *LogFilter
+		// logFile file all the log lines are written to, whatever the filter
+		logFile *LogFile
+		// diagnosis causes of failure found in all the log lines
+		diagnosis *Diagnosis
+	}
+)
+
+// out output of the CLI
+var out = NewOutput(OutputText, os.Stdout)
+
+// NewOutput create an output printing to w with the given format
+func NewOutput(format string, w io.Writer) *Output {
+	return &Output{
+		Format:    format,
+		w:         w,
+		diagnosis: &Diagnosis{},
+	}
+}
+
+// SetFormat change the format of the output, which must be text or json
+func (o *Output) SetFormat(format string) error {
+	if format != OutputText && format != OutputJSON {
+		return fmt.Errorf("--output must be %s or %s", OutputText, OutputJSON)
+	}
+	o.Format = format
+	return nil
+}
+
+// SetLogFilter filter the log lines printed
+func (o *Output) SetLogFilter(filter *LogFilter) {
+	o.mu.Lock()
+	o.logFilter = filter
+	o.mu.Unlock()
+}
+
+// SetLogFile write all the log lines to the file
+func (o *Output) SetLogFile(f *LogFile) {
+	o.mu.Lock()
+	o.logFile = f
+	o.mu.Unlock()
+}
+
+// CloseLogFile close the log file, printing its last lines in text output when the job isn't COMPLETED
+func (o *Output) CloseLogFile(job *JobStatus) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.logFile == nil {
+		return
+	}
+
+	if job.Status != JobStatusCOMPLETED && !o.JSON() {
+		PrintTail(o.w, o.logFile.Tail())
+	}
+	if err := o.logFile.Close(); err != nil {
+		log.Printf("Unable to close the log file: %s", err)
+	}
+	log.Printf("Logs of job %s written to %s", job.ID, o.logFile.Path)
+	o.logFile = nil
+}
+
+// Colored test if the output can be colored: text printed to a terminal, unless NO_COLOR is set
+func (o *Output) Colored() bool {
+	f, ok := o.w.(*os.File)
+	return ok && !o.JSON() && os.Getenv("NO_COLOR") == "" && utils.IsTerminal(f)
+}
+
+// JSON test if the output is printed as json events
+func (o *Output) JSON() bool {
+	return o.Format == OutputJSON
+}
+
+// Submitted print the job submission
+func (o *Output) Submitted(job *JobStatus) {
+	o.mu.Lock()
+	o.lastStatus = job.Status
+	o.mu.Unlock()
+
+	if !o.JSON() {
+		log.Printf("Job '%s' submitted with id %s", job.Name, job.ID)
+		return
+	}
+	o.emit(&Event{Type: EventSubmitted, JobID: job.ID, JobName: job.Name, Status: job.Status})
+}
+
+// Status print the status of the job when it changed since the last call
+func (o *Output) Status(job *JobStatus) {
+	o.mu.Lock()
+	changed := o.lastStatus != job.Status
+	o.lastStatus = job.Status
+	o.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if !o.JSON() {
+		log.Printf("Job is %s", job.Status)
+		return
+	}
+	o.emit(&Event{Type: EventStatus, JobID: job.ID, Status: job.Status})
+}
+
+// Log print a log line of the job, unless it's filtered out
+func (o *Output) Log(jobID string, jLog *Log) {
+	o.mu.Lock()
+	if o.logFile != nil {
+		if err := o.logFile.WriteLine(jLog.Content); err != nil {
+			log.Printf("Unable to write the log file, the logs aren't written to it anymore: %s", err)
+			if err := o.logFile.Close(); err != nil {
+				log.Printf("Unable to close the log file: %s", err)
+			}
+			o.logFile = nil
+		}
+	}
+	o.diagnosis.Scan(jLog.Content)
+	line, ok := o.logFilter.Match(jLog.Content)
+	if ok && !o.JSON() {
+		fmt.Fprintln(o.w, o.logFilter.Highlight(jLog.Content, line))
+	}
+	o.mu.Unlock()
+
+	if ok && o.JSON() {
+		o.emit(&Event{Type: EventLog, JobID: jobID, Log: jLog, Level: line.Level})
+	}
+}
+
+// Job print the job, with its exit code once completed
+func (o *Output) Job(job *JobStatus) {
+	if !o.JSON() {
+		log.Printf("Job status is : %s", job.Status)
+		if job.Status == JobStatusCOMPLETED {
+			log.Printf("Job exit code : %v", job.ReturnCode)
+		}
+		return
+	}
+	o.emit(&Event{Type: EventJob, JobID: job.ID, Status: job.Status, Job: job})
+}
+
+// LogsAddress print where the logs of the job can be downloaded
+func (o *Output) LogsAddress(jobID string, logsAddress string) {
+	if !o.JSON() {
+		log.Printf("You can download your logs at %s", logsAddress)
+		return
+	}
+	o.emit(&Event{Type: EventLogsAddress, JobID: jobID, LogsAddress: logsAddress})
+}
+
+// Diagnosis print the causes of the failure of the job found in the printed log lines and in the downloaded log files
+func (o *Output) Diagnosis(job *JobStatus, files []string) {
+	o.mu.Lock()
+	for _, file := range files {
+		if err := o.diagnosis.ScanFile(file); err != nil {
+			log.Printf("Unable to scan %s: %s", file, err)
+		}
+	}
+	findings := o.diagnosis.Findings(job)
+	o.mu.Unlock()
+
+	if len(findings) == 0 {
+		return
+	}
+	if !o.JSON() {
+		log.Printf("Job %s failed, possible causes:", job.ID)
+		for _, finding := range findings {
+			log.Printf("- %s", finding.Line)
+			log.Printf("  hint: %s", finding.Hint)
+		}
+		return
+	}
+	o.emit(&Event{Type: EventDiagnosis, JobID: job.ID, Status: job.Status, Findings: findings})
+}
+
+// LogsDownloaded print the local files of the downloaded logs of the job, one per line
+func (o *Output) LogsDownloaded(jobID string, files []string) {
+	if !o.JSON() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		for _, file := range files {
+			fmt.Fprintln(o.w, file)
+		}
+		return
+	}
+	o.emit(&Event{Type: EventLogsDownloaded, JobID: jobID, Files: files})
+}
+
+// Killed print the job kill
+func (o *Output) Killed(jobID string) {
+	if !o.JSON() {
+		log.Printf("Job %s killed", jobID)
+		return
+	}
+	o.emit(&Event{Type: EventKilled, JobID: jobID})
+}
+
+// emit print the event as a json line
+func (o *Output) emit(event *Event) {
+	event.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	content, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Unable to print %s event: %s", event.Type, err)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, err := fmt.Fprintf(o.w, "%s\n", content); err != nil {
+		log.Printf("Unable to print %s event: %s", event.Type, err)
+	}
+}
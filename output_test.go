@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// readEvents decode the newline-delimited json events printed
+func readEvents(t *testing.T, buf *bytes.Buffer) []*Event {
+	var events []*Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		event := &Event{}
+		if err := json.Unmarshal([]byte(line), event); err != nil {
+			t.Fatalf("invalid event %q: %s", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestOutputJSON(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewOutput(OutputJSON, &buf)
+
+	job := &JobStatus{ID: JobID, Name: "hello", Status: JobStatusPENDING}
+	o.Submitted(job)
+	o.Status(job)
+	o.Status(job)
+	job = &JobStatus{ID: JobID, Name: "hello", Status: JobStatusRUNNING}
+	o.Status(job)
+	o.Log(JobID, &Log{ID: 1, Content: "My first log", Timestamp: "2019-12-03T09:40:15Z"})
+	job = &JobStatus{ID: JobID, Name: "hello", Status: JobStatusCOMPLETED, ReturnCode: 0}
+	o.Job(job)
+	o.LogsAddress(JobID, "https://storage.gra.cloud.ovh.net/v1/AUTH_xxx/odp-logs?prefix="+JobID)
+
+	events := readEvents(t, &buf)
+	expected := []string{EventSubmitted, EventStatus, EventLog, EventJob, EventLogsAddress}
+	if len(events) != len(expected) {
+		t.Fatalf("unexpected events %q", buf.String())
+	}
+
+	for i, event := range events {
+		if event.Type != expected[i] {
+			t.Errorf("event %d is %s instead of %s", i, event.Type, expected[i])
+		}
+		if event.JobID != JobID || event.Time == "" {
+			t.Errorf("event %d is incomplete: %+v", i, event)
+		}
+	}
+
+	if events[1].Status != JobStatusRUNNING {
+		t.Fail()
+	}
+
+	if events[2].Log == nil || events[2].Log.Content != "My first log" {
+		t.Fail()
+	}
+
+	if events[3].Job == nil || events[3].Job.Status != JobStatusCOMPLETED {
+		t.Fail()
+	}
+}
+
+func TestOutputText(t *testing.T) {
+	var buf bytes.Buffer
+	o := NewOutput(OutputText, &buf)
+
+	o.Log(JobID, &Log{ID: 1, Content: "My first log"})
+
+	if buf.String() != "My first log\n" {
+		t.Fail()
+	}
+}
+
+func TestOutputLogsDownloaded(t *testing.T) {
+	files := []string{"logs/driver.log", "logs/" + JobID + ".log"}
+
+	var buf bytes.Buffer
+	NewOutput(OutputText, &buf).LogsDownloaded(JobID, files)
+	if buf.String() != strings.Join(files, "\n")+"\n" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+
+	buf.Reset()
+	NewOutput(OutputJSON, &buf).LogsDownloaded(JobID, files)
+	events := readEvents(t, &buf)
+	if len(events) != 1 || events[0].Type != EventLogsDownloaded || strings.Join(events[0].Files, ",") != strings.Join(files, ",") {
+		t.Errorf("unexpected events %q", buf.String())
+	}
+}
+
+func TestOutputSetFormat(t *testing.T) {
+	o := NewOutput(OutputText, &bytes.Buffer{})
+
+	if err := o.SetFormat(OutputJSON); err != nil || !o.JSON() {
+		t.Fail()
+	}
+
+	if err := o.SetFormat("yaml"); err == nil {
+		t.Fail()
+	}
+}
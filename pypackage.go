@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"data-processing-spark-submit/upload"
+)
+
+// PythonRequirements file listing the dependencies of a python package
+const PythonRequirements = "requirements.txt"
+
+// PipInstall command installing the requirements of a python package, followed by --target DIR -r REQUIREMENTS
+var PipInstall = []string{"python3", "-m", "pip", "install", "--quiet"}
+
+// CompiledExtensions extensions of the compiled python modules, built for the local platform
+// and which can't be imported from a zip archive
+var CompiledExtensions = []string{".so", ".pyd"}
+
+// PythonPackageZip check the directory of a python package and return the name of its zip archive,
+// without building it
+func PythonPackageZip(dir string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(absDir)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s isn't a directory", dir)
+	}
+	return filepath.Base(absDir) + ".zip", nil
+}
+
+// PackagePython zip a local python package (a directory with a __init__.py, zipped under its name) or source
+// directory (its content is zipped) into destDir, with the dependencies listed in its requirements.txt.
+// The compiled extensions are rejected. It returns the path of the zip archive
+func PackagePython(dir string, destDir string) (string, error) {
+	zipName, err := PythonPackageZip(dir)
+	if err != nil {
+		return "", err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := ""
+	if _, err := os.Stat(filepath.Join(absDir, "__init__.py")); err == nil {
+		prefix = filepath.Base(absDir)
+	}
+
+	entries, err := upload.ZipDir(absDir, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	requirements := filepath.Join(absDir, PythonRequirements)
+	if _, err := os.Stat(requirements); err == nil {
+		depsDir, err := os.MkdirTemp("", "ovh-spark-submit-deps")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(depsDir)
+
+		log.Printf("Installing the requirements of %s ...", dir)
+		command := append(append([]string{}, PipInstall...), "--target", depsDir, "-r", requirements)
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("unable to install %s: %w", requirements, err)
+		}
+
+		deps, err := upload.ZipDir(depsDir, "")
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, deps...)
+	}
+
+	// only pure python code can be imported from the archive on the cluster
+	for _, entry := range entries {
+		if inTheList(strings.ToLower(filepath.Ext(entry.Name)), CompiledExtensions) {
+			return "", fmt.Errorf("%s is a compiled extension, which can't be imported from the archive: "+
+				"only pure python packages and dependencies can be zipped", entry.Name)
+		}
+	}
+
+	zipPath := filepath.Join(destDir, zipName)
+	file, err := os.Create(zipPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := upload.Zip(file, entries); err != nil {
+		return "", err
+	}
+	log.Printf("Python package %s zipped in %s", dir, zipPath)
+	return zipPath, file.Close()
+}
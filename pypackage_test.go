@@ -0,0 +1,113 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// zipNames names of the entries of a zip archive
+func zipNames(t *testing.T, path string) []string {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestPackagePython(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mypkg")
+	if err := os.MkdirAll(filepath.Join(dir, "__pycache__"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"__init__.py", "helpers.py", "__pycache__/helpers.cpython-39.pyc"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(""), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	zipPath, err := PackagePython(dir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filepath.Base(zipPath) != "mypkg.zip" {
+		t.Errorf("unexpected archive %s", zipPath)
+	}
+
+	// a package is zipped under its name
+	names := zipNames(t, zipPath)
+	if len(names) != 2 || names[0] != "mypkg/__init__.py" || names[1] != "mypkg/helpers.py" {
+		t.Errorf("unexpected entries %q", names)
+	}
+}
+
+func TestPackagePythonRequirements(t *testing.T) {
+	pipInstall := PipInstall
+	defer func() {
+		PipInstall = pipInstall
+	}()
+	// fake pip installing a "dep" package in --target
+	PipInstall = []string{"sh", "-c", `mkdir -p "$2/dep" && touch "$2/dep/__init__.py"`, "pip"}
+
+	dir := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"main.py", PythonRequirements} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(""), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	zipPath, err := PackagePython(dir, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a source directory is zipped at the root, with the requirements
+	names := zipNames(t, zipPath)
+	if len(names) != 3 || names[0] != "dep/__init__.py" || names[1] != "main.py" || names[2] != PythonRequirements {
+		t.Errorf("unexpected entries %q", names)
+	}
+}
+
+func TestPackagePythonErr(t *testing.T) {
+	if _, err := PackagePython("testdata/py.py", t.TempDir()); err == nil {
+		t.Fail()
+	}
+
+	if _, err := PackagePython("testdata/missing", t.TempDir()); err == nil {
+		t.Fail()
+	}
+}
+
+func TestPackagePythonCompiled(t *testing.T) {
+	pipInstall := PipInstall
+	defer func() {
+		PipInstall = pipInstall
+	}()
+	// fake pip installing a dependency with a compiled extension
+	PipInstall = []string{"sh", "-c", `mkdir -p "$2/numpy" && touch "$2/numpy/__init__.py" "$2/numpy/_core.cpython-39-x86_64-linux-gnu.so"`, "pip"}
+
+	dir := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"main.py", PythonRequirements} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(""), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := PackagePython(dir, t.TempDir()); err == nil {
+		t.Error("a compiled extension must be rejected")
+	}
+}
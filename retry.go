@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+const (
+	DefaultRetryMaxAttempts = 5
+	DefaultRetryBackoff     = time.Second
+	DefaultRetryMaxBackoff  = 30 * time.Second
+	DefaultRetryJitter      = 0.5
+)
+
+// RetryPolicy exponential backoff applied to the failed API calls which can be retried
+type RetryPolicy struct {
+	// MaxAttempts number of calls before giving up, including the first one
+	MaxAttempts int
+	// Backoff delay before the first retry, doubled on each retry
+	Backoff time.Duration
+	// MaxBackoff maximal delay between two retries
+	MaxBackoff time.Duration
+	// Jitter fraction (between 0 and 1) of the delay randomly removed to spread the retries
+	Jitter float64
+
+	sleep func(time.Duration)
+}
+
+// NewRetryPolicy create a retry policy, using the default value of each zero parameter
+func NewRetryPolicy(maxAttempts int, backoff, maxBackoff time.Duration, jitter float64) *RetryPolicy {
+	r := &RetryPolicy{
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+		MaxBackoff:  maxBackoff,
+		Jitter:      jitter,
+		sleep:       time.Sleep,
+	}
+
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = DefaultRetryMaxAttempts
+	}
+	if r.Backoff <= 0 {
+		r.Backoff = DefaultRetryBackoff
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	if r.Jitter <= 0 || r.Jitter > 1 {
+		r.Jitter = DefaultRetryJitter
+	}
+	return r
+}
+
+// Do call until it succeeds, fails with an error which can't be retried or the attempts are exhausted
+func (r *RetryPolicy) Do(call func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = call()
+		if err == nil || !IsRetryable(err) || attempt >= r.MaxAttempts {
+			return err
+		}
+
+		delay := r.Delay(attempt)
+		log.Printf("API call failed: %s, retrying in %s (%d/%d)", err, delay.Round(time.Millisecond), attempt, r.MaxAttempts-1)
+		r.sleep(delay)
+	}
+}
+
+// Delay before the given retry
+func (r *RetryPolicy) Delay(attempt int) time.Duration {
+	delay := r.MaxBackoff
+	if attempt < 32 {
+		if backoff := r.Backoff << (attempt - 1); backoff > 0 && backoff < r.MaxBackoff {
+			delay = backoff
+		}
+	}
+	return delay - time.Duration(r.Jitter*rand.Float64()*float64(delay))
+}
+
+// IsRetryable test if a failed API call can be retried: API server errors, rate limiting and network errors.
+// Other API errors (4xx) are fatal
+func IsRetryable(err error) bool {
+	var apiErr *ovh.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= http.StatusInternalServerError
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ovh/go-ovh/ovh"
+)
+
+// newTestRetryPolicy create a retry policy which doesn't sleep and records its delays
+func newTestRetryPolicy(maxAttempts int, delays *[]time.Duration) *RetryPolicy {
+	r := NewRetryPolicy(maxAttempts, time.Second, 4*time.Second, 0.5)
+	r.sleep = func(delay time.Duration) {
+		*delays = append(*delays, delay)
+	}
+	return r
+}
+
+func TestRetryPolicyDo(t *testing.T) {
+	var delays []time.Duration
+	r := newTestRetryPolicy(5, &delays)
+
+	calls := 0
+	err := r.Do(func() error {
+		calls++
+		if calls < 3 {
+			return &ovh.APIError{Code: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+
+	if err != nil || calls != 3 || len(delays) != 2 {
+		t.Errorf("unexpected result: %v after %d calls and %d retries", err, calls, len(delays))
+	}
+}
+
+func TestRetryPolicyDoFatal(t *testing.T) {
+	var delays []time.Duration
+	r := newTestRetryPolicy(5, &delays)
+
+	calls := 0
+	err := r.Do(func() error {
+		calls++
+		return &ovh.APIError{Code: http.StatusNotFound}
+	})
+
+	if err == nil || calls != 1 || len(delays) != 0 {
+		t.Errorf("unexpected result: %v after %d calls and %d retries", err, calls, len(delays))
+	}
+}
+
+func TestRetryPolicyDoExhausted(t *testing.T) {
+	var delays []time.Duration
+	r := newTestRetryPolicy(3, &delays)
+
+	calls := 0
+	err := r.Do(func() error {
+		calls++
+		return &ovh.APIError{Code: http.StatusInternalServerError}
+	})
+
+	if err == nil || calls != 3 || len(delays) != 2 {
+		t.Errorf("unexpected result: %v after %d calls and %d retries", err, calls, len(delays))
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	r := NewRetryPolicy(10, time.Second, 4*time.Second, 0.5)
+
+	for attempt, max := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, 4 * time.Second} {
+		delay := r.Delay(attempt + 1)
+		if delay > max || delay < max/2 {
+			t.Errorf("delay %s of retry %d isn't between %s and %s", delay, attempt+1, max/2, max)
+		}
+	}
+
+	if delay := r.Delay(100); delay > 4*time.Second || delay < 2*time.Second {
+		t.Errorf("delay %s of retry 100 isn't capped", delay)
+	}
+}
+
+func TestNewRetryPolicyDefaults(t *testing.T) {
+	r := NewRetryPolicy(0, 0, 0, 0)
+
+	if r.MaxAttempts != DefaultRetryMaxAttempts || r.Backoff != DefaultRetryBackoff ||
+		r.MaxBackoff != DefaultRetryMaxBackoff || r.Jitter != DefaultRetryJitter {
+		t.Fail()
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestIsRetryable(t *testing.T) {
+	for _, code := range []int{429, 500, 502, 503, 504} {
+		if !IsRetryable(&ovh.APIError{Code: code}) {
+			t.Errorf("%d should be retryable", code)
+		}
+	}
+
+	for _, code := range []int{400, 401, 403, 404, 409, 422} {
+		if IsRetryable(&ovh.APIError{Code: code}) {
+			t.Errorf("%d shouldn't be retryable", code)
+		}
+	}
+
+	if !IsRetryable(fmt.Errorf("Get: %w", timeoutError{})) {
+		t.Error("timeout should be retryable")
+	}
+
+	if !IsRetryable(&net.OpError{Op: "dial", Err: errors.New("connection refused")}) {
+		t.Error("connection errors should be retryable")
+	}
+
+	if IsRetryable(errors.New("invalid character")) {
+		t.Error("other errors shouldn't be retryable")
+	}
+}
+
+func TestGetStatusRetry(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RequestURI == "/auth/time" {
+			fmt.Fprint(w, MockTime)
+			return
+		}
+
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			fmt.Fprint(w, `{"message":"Bad Gateway"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"%s","status":"RUNNING"}`, JobID)
+	}))
+	defer ts.Close()
+
+	ovhClient, _ := ovh.NewClient(ts.URL, MockApplicationKey, MockApplicationSecret, MockConsumerKey)
+	var delays []time.Duration
+	client := &Client{
+		OVH:   ovhClient,
+		Retry: newTestRetryPolicy(3, &delays),
+	}
+
+	job, err := client.GetStatus(ProjectID, JobID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if job.Status != JobStatusRUNNING || calls != 2 {
+		t.Fail()
+	}
+}
+
+func TestSubmitRetryDoesNotSubmitTwice(t *testing.T) {
+	var submitted []*JobStatus
+	posts, lists := 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RequestURI == "/auth/time" {
+			fmt.Fprint(w, MockTime)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jobsPath := fmt.Sprintf(DataProcessingList, ProjectID)
+		switch {
+		case r.Method == http.MethodPost:
+			// the job is created but the response is lost
+			params := &JobSubmit{}
+			json.NewDecoder(r.Body).Decode(params)
+			posts++
+			submitted = append(submitted, &JobStatus{
+				ID:               fmt.Sprintf("job-%d", posts),
+				Name:             params.Name,
+				Status:           JobStatusPENDING,
+				CreationDate:     time.Now().UTC().Format(time.RFC3339),
+				EngineParameters: params.EngineParameters,
+			})
+			w.WriteHeader(http.StatusGatewayTimeout)
+			fmt.Fprint(w, `{"message":"Gateway Timeout"}`)
+		case r.URL.Path == jobsPath:
+			lists++
+			jobIDs := []string{"old-job"}
+			if posts > 0 {
+				jobIDs = append(jobIDs, "other-job", "deleted-job")
+			}
+			for _, job := range submitted {
+				jobIDs = append(jobIDs, job.ID)
+			}
+			json.NewEncoder(w).Encode(jobIDs)
+		case strings.HasSuffix(r.URL.Path, "/other-job"):
+			// job of a concurrent run with the same name, submitted with other parameters
+			json.NewEncoder(w).Encode(&JobStatus{
+				ID:               "other-job",
+				Name:             "hello",
+				CreationDate:     time.Now().UTC().Format(time.RFC3339),
+				EngineParameters: []*JobEngineParameter{{Name: ParameterMainCode, Value: "other.py"}},
+			})
+		case strings.HasSuffix(r.URL.Path, "/old-job"):
+			// listed before the submission, never fetched
+			t.Errorf("unexpected fetch of %s", r.URL.Path)
+		case strings.HasSuffix(r.URL.Path, "/deleted-job"):
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Not Found"}`)
+		default:
+			json.NewEncoder(w).Encode(submitted[0])
+		}
+	}))
+	defer ts.Close()
+
+	ovhClient, _ := ovh.NewClient(ts.URL, MockApplicationKey, MockApplicationSecret, MockConsumerKey)
+	var delays []time.Duration
+	client := &Client{
+		OVH:   ovhClient,
+		Retry: newTestRetryPolicy(3, &delays),
+	}
+
+	params := &JobSubmit{Name: "hello", EngineParameters: []*JobEngineParameter{
+		{Name: ParameterMainCode, Value: "main.py"},
+		{Name: ParameterArgs, Value: "1000"},
+	}}
+	job, err := client.Submit(ProjectID, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if posts != 1 || job.ID != "job-1" {
+		t.Errorf("job %s submitted %d times", job.ID, posts)
+	}
+	// the name given by the user is kept
+	if job.Name != "hello" || params.Name != "hello" || lists != 2 {
+		t.Errorf("unexpected job name %s, listed %d times", job.Name, lists)
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SparkConfFlags spark properties which must be set with their dedicated flag
+var SparkConfFlags = map[string]string{
+	"spark.driver.cores":            "--driver-cores",
+	"spark.driver.memory":           "--driver-memory",
+	"spark.driver.memoryOverhead":   "--driver-memoryOverhead",
+	"spark.executor.cores":          "--executor-cores",
+	"spark.executor.instances":      "--num-executors",
+	"spark.executor.memory":         "--executor-memory",
+	"spark.executor.memoryOverhead": "--executor-memoryOverhead",
+	"spark.jars.packages":           "--packages",
+	"spark.jars.repositories":       "--repositories",
+	"spark.submit.pyFiles":          "--py-files",
+	"spark.jars":                    "--jars",
+	"spark.files":                   "--files",
+}
+
+// ParseSparkConf parse a list of "key=value" spark properties
+func ParseSparkConf(values []string) (map[string]string, error) {
+	conf := make(map[string]string, len(values))
+	for _, value := range values {
+		index := strings.Index(value, "=")
+		if index < 0 {
+			return nil, fmt.Errorf("%s must be formatted as key=value", value)
+		}
+
+		key := strings.TrimSpace(value[:index])
+		if err := ValidSparkConfKey(key); err != nil {
+			return nil, err
+		}
+		conf[key] = strings.TrimSpace(value[index+1:])
+	}
+	return conf, nil
+}
+
+// ValidSparkConfKey check that the key is a spark property which isn't set by a dedicated flag
+func ValidSparkConfKey(key string) error {
+	if !strings.HasPrefix(key, "spark.") || len(key) == len("spark.") {
+		return fmt.Errorf("%s isn't a spark property, it must start with \"spark.\"", key)
+	}
+	if strings.ContainsAny(key, " \t=") {
+		return fmt.Errorf("%s isn't a valid spark property", key)
+	}
+	if flag, ok := SparkConfFlags[key]; ok {
+		return fmt.Errorf("%s must be set with %s", key, flag)
+	}
+	return nil
+}
+
+// FormatProperties format the spark properties as a spark-defaults style properties file, sorted by key
+func FormatProperties(properties map[string]string) string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s %s\n", key, properties[key])
+	}
+	return b.String()
+}
+
+// PropertiesFileName name of the properties file generated with the content, named after its hash
+// so that concurrent jobs with other properties don't override it
+func PropertiesFileName(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "spark-" + hex.EncodeToString(sum[:])[:16] + ".conf"
+}
+
+// splitConf dispatch the --conf values between the spark properties (key=value)
+// and the path of the configuration.ini, kept for backward compatibility
+func (a *CLIArgs) splitConf() error {
+	for _, value := range a.Conf {
+		if strings.Contains(value, "=") {
+			a.SparkConf = append(a.SparkConf, value)
+			continue
+		}
+
+		if a.Config != nil {
+			return fmt.Errorf("--conf %s: the configuration.ini path is already set, use --spark-conf for spark properties", value)
+		}
+		path := value
+		a.Config = &path
+		log.Printf("--conf %s: setting the configuration.ini path with --conf is deprecated, use --config", value)
+	}
+	a.Conf = nil
+	return nil
+}
+
+// LoadPropertiesFile parse a local spark-defaults style properties file: one "key value",
+// "key=value" or "key: value" property per line, blank lines and lines starting with # or ! are ignored
+func LoadPropertiesFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]string{}
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+
+		index := strings.IndexAny(line, " \t=:")
+		if index < 0 {
+			return nil, fmt.Errorf("%s:%d: %s must be formatted as \"key value\"", path, i+1, line)
+		}
+		key := line[:index]
+		value := strings.TrimSpace(line[index:])
+		value = strings.TrimSpace(strings.TrimLeft(value, "=:"))
+
+		if !strings.HasPrefix(key, "spark.") || len(key) == len("spark.") {
+			return nil, fmt.Errorf("%s:%d: %s isn't a spark property, it must start with \"spark.\"", path, i+1, key)
+		}
+		properties[key] = value
+	}
+	return properties, nil
+}
+
+// IsLocalPropertiesFile test if the properties file is a local file to inline, otherwise it's the name
+// of a properties file already in the container of the job
+func IsLocalPropertiesFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// sparkMemoryProperties spark properties of the memory sizes, in MiB without unit
+var sparkMemoryProperties = []string{
+	"spark.driver.memory",
+	"spark.driver.memoryOverhead",
+	"spark.executor.memory",
+	"spark.executor.memoryOverhead",
+}
+
+// applyProperties set the resource flags which haven't been given from the spark properties
+// and return the other properties. Flags always take precedence over the properties.
+// As in spark, a memory size without unit is in MiB
+func (a *CLIArgs) applyProperties(properties map[string]string) map[string]string {
+	flags := map[string]*string{
+		"spark.driver.cores":            &a.DriverCores,
+		"spark.driver.memory":           &a.DriverMemory,
+		"spark.driver.memoryOverhead":   &a.DriverMemoryOverhead,
+		"spark.executor.cores":          &a.ExecutorCores,
+		"spark.executor.instances":      &a.ExecutorNum,
+		"spark.executor.memory":         &a.ExecutorMemory,
+		"spark.executor.memoryOverhead": &a.ExecutorMemoryOverhead,
+		"spark.jars.packages":           &a.Packages,
+		"spark.jars.repositories":       &a.Repositories,
+	}
+
+	others := map[string]string{}
+	for key, value := range properties {
+		if flag, ok := flags[key]; ok {
+			if *flag == "" {
+				if _, err := strconv.ParseUint(value, 10, 64); err == nil && inTheList(key, sparkMemoryProperties) {
+					value += "M"
+				}
+				*flag = value
+			}
+			continue
+		}
+		others[key] = value
+	}
+	return others
+}
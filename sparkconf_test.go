@@ -0,0 +1,143 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"data-processing-spark-submit/utils"
+)
+
+func TestParseSparkConf(t *testing.T) {
+	conf, err := ParseSparkConf([]string{"spark.sql.shuffle.partitions=200", "spark.driver.extraJavaOptions=-Dkey=value"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf["spark.sql.shuffle.partitions"] != "200" {
+		t.Fail()
+	}
+
+	if conf["spark.driver.extraJavaOptions"] != "-Dkey=value" {
+		t.Fail()
+	}
+}
+
+func TestParseSparkConfErr(t *testing.T) {
+	for _, value := range []string{"spark.sql.shuffle.partitions", "sql.shuffle.partitions=200", "spark.=1", "spark.driver.memory=4G", "spark.jars=lib.jar", "spark.my key=1"} {
+		if _, err := ParseSparkConf([]string{value}); err == nil {
+			t.Errorf("%s must be invalid", value)
+		}
+	}
+}
+
+func TestSplitConf(t *testing.T) {
+	a := &CLIArgs{Conf: []string{"spark.sql.shuffle.partitions=200", "testdata/configuration.ini"}}
+	if err := a.splitConf(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(a.SparkConf) != 1 || a.SparkConf[0] != "spark.sql.shuffle.partitions=200" {
+		t.Fail()
+	}
+
+	if a.Config == nil || *a.Config != "testdata/configuration.ini" {
+		t.Fail()
+	}
+
+	config := "configuration.ini"
+	a = &CLIArgs{Config: &config, Conf: []string{"testdata/configuration.ini"}}
+	if err := a.splitConf(); err == nil {
+		t.Fail()
+	}
+}
+
+func TestLoadPropertiesFile(t *testing.T) {
+	properties, err := LoadPropertiesFile("testdata/spark-defaults.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(properties) != 5 {
+		t.Fatalf("unexpected properties %v", properties)
+	}
+
+	if properties["spark.driver.memory"] != "2g" || properties["spark.executor.memory"] != "2g" || properties["spark.executor.instances"] != "4" {
+		t.Fail()
+	}
+
+	if properties["spark.serializer"] != "org.apache.spark.serializer.KryoSerializer" {
+		t.Fail()
+	}
+}
+
+func TestLoadPropertiesFileErr(t *testing.T) {
+	if _, err := LoadPropertiesFile("testdata/missing.conf"); err == nil {
+		t.Fail()
+	}
+
+	path := filepath.Join(t.TempDir(), "spark-defaults.conf")
+	if err := os.WriteFile(path, []byte("sql.shuffle.partitions 200\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPropertiesFile(path); err == nil {
+		t.Fail()
+	}
+}
+
+func TestApplyProperties(t *testing.T) {
+	a := &CLIArgs{DriverMemory: "4G"}
+	others := a.applyProperties(map[string]string{
+		"spark.driver.memory":           "2g",
+		"spark.executor.memory":         "2g",
+		"spark.executor.memoryOverhead": "512",
+		"spark.executor.cores":          "2",
+		"spark.eventLog.enabled":        "true",
+	})
+
+	// flags take precedence
+	if a.DriverMemory != "4G" || a.ExecutorMemory != "2g" || a.ExecutorCores != "2" {
+		t.Fail()
+	}
+
+	// a memory size without unit is in MiB
+	if size, err := utils.ParseSize(a.ExecutorMemoryOverhead); err != nil || size != 512 {
+		t.Errorf("unexpected memory overhead %s: %v", a.ExecutorMemoryOverhead, err)
+	}
+
+	if len(others) != 1 || others["spark.eventLog.enabled"] != "true" {
+		t.Fail()
+	}
+}
+
+func TestFormatProperties(t *testing.T) {
+	content := FormatProperties(map[string]string{"spark.task.maxFailures": "8", "spark.eventLog.enabled": "false"})
+	if content != "spark.eventLog.enabled false\nspark.task.maxFailures 8\n" {
+		t.Errorf("unexpected properties %q", content)
+	}
+
+	// read back as a properties file
+	path := filepath.Join(t.TempDir(), PropertiesFileName(content))
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	properties, err := LoadPropertiesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(properties) != 2 || properties["spark.task.maxFailures"] != "8" {
+		t.Errorf("unexpected properties %v", properties)
+	}
+}
+
+func TestPropertiesFileName(t *testing.T) {
+	name := PropertiesFileName("spark.eventLog.enabled false\n")
+	if name != PropertiesFileName("spark.eventLog.enabled false\n") || len(name) != len("spark-.conf")+16 {
+		t.Errorf("unexpected name %s", name)
+	}
+
+	// a change of properties uploads a new file
+	if name == PropertiesFileName("spark.eventLog.enabled true\n") {
+		t.Error("different properties must have different names")
+	}
+}
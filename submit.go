@@ -4,9 +4,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"strings"
 	"syscall"
@@ -26,8 +30,22 @@ import (
 
 const (
 	LoopWaitSecond = 2
+	KillWaitSecond = 120
 	OVHConfig      = "ovh"
 	SwiftConfig    = "swift"
+	S3Config       = "s3"
+)
+
+const (
+	// OnSignalAsk ask whether to kill the job when a TTY is available, otherwise kill it
+	OnSignalAsk = "ask"
+	// OnSignalKill kill the job and wait for the API to acknowledge it
+	OnSignalKill = "kill"
+	// OnSignalDetach stop following the job, leaving it running
+	OnSignalDetach = "detach"
+
+	// UploadVersionHash version of the uploaded files computed from their content
+	UploadVersionHash = "hash"
 )
 
 var (
@@ -37,7 +55,15 @@ var (
 
 var (
 	defaultConfigPath  = "configuration.ini"
-	SupportedProtocols = []string{SwiftConfig}
+	SupportedProtocols = []string{SwiftConfig, S3Config}
+	OnSignalPolicies   = []string{OnSignalAsk, OnSignalKill, OnSignalDetach}
+)
+
+var (
+	// ErrJobKilled returned by Loop when the job has been killed on a signal
+	ErrJobKilled = errors.New("job killed")
+	// ErrDetached returned by Loop when it stopped following the job on a signal
+	ErrDetached = errors.New("detached from job")
 )
 
 type (
@@ -46,55 +72,147 @@ type (
 		ApplicationKey    string `ini:"application_key"`
 		ApplicationSecret string `ini:"application_secret"`
 		ConsumerKey       string `ini:"consumer_key"`
+		// retry policy of the failed API calls, defaults are used when not set
+		RetryMaxAttempts int           `ini:"retry_max_attempts"`
+		RetryBackoff     time.Duration `ini:"retry_backoff"`
+		RetryMaxBackoff  time.Duration `ini:"retry_max_backoff"`
+		RetryJitter      float64       `ini:"retry_jitter"`
 	}
 
 	CLIArgs struct {
-		JobName                string   `json:"jobname" ini:"jobname" arg:"env:JOB_NAME" help:"Job name (can be set with ENV vars JOB_NAME)"`
-		Region                 string   `json:"region" ini:"region" arg:"env:OS_REGION" default:"GRA" help:"Openstack region of the job (can be set with ENV vars OS_REGION)"`
-		ProjectID              string   `json:"projectid" ini:"projectid" arg:"env:OS_PROJECT_ID" help:"Openstack ProjectID (can be set with ENV vars OS_PROJECT_ID)"`
-		SparkVersion           string   `json:"spark-version" ini:"spark-version" arg:"--spark-version,env:SPARK_VERSION" default:"2.4.3" help:"Version of spark (can be set with ENV vars SPARK_VERSION)"`
-		Upload                 string   `json:"upload" ini:"upload" arg:"env:UPLOAD" help:"Comma-delimited list of file path/dir to upload before running the job (can be set with ENV vars UPLOAD)"`
-		Class                  string   `json:"class" ini:"class" help:"main-class"`
-		DriverCores            string   `json:"driver-cores" ini:"driver-cores" arg:"--driver-cores"`
-		DriverMemory           string   `json:"driver-memory" ini:"driver-memory" arg:"--driver-memory" help:"Driver memory in (gigi/mebi)bytes (eg. \"10G\")"`
-		DriverMemoryOverhead   string   `json:"driver-memoryOverhead" ini:"driver-memoryOverhead" arg:"--driver-memoryOverhead" help:"Driver memoryOverhead in (gigi/mebi)bytes (eg. \"10G\")"`
-		ExecutorCores          string   `json:"executor-cores" ini:"executor-cores" arg:"--executor-cores"`
-		ExecutorNum            string   `json:"num-executors" ini:"num-executors" arg:"--num-executors"`
-		ExecutorMemory         string   `json:"executor-memory" ini:"executor-memory" arg:"--executor-memory" help:"Executor memory in (gigi/mebi)bytes (eg. \"10G\")"`
-		ExecutorMemoryOverhead string   `json:"executor-memoryOverhead" ini:"executor-memoryOverhead" arg:"--executor-memoryOverhead" help:"Executor memory in (gigi/mebi)bytes (eg. \"10G\")"`
-		Packages               string   `json:"packages" ini:"packages" arg:"--packages" help:"Comma-delimited list of Maven coordinates"`
-		Repositories           string   `json:"repositories" ini:"repositories" arg:"--repositories" help:"Comma-delimited list of additional repositories (or resolvers in SBT)"`
-		PropertiesFile         string   `json:"properties-file" ini:"properties-file" arg:"--properties-file" help:"Read properties from the given file"`
-		TTL                    string   `json:"ttl" ini:"ttl" arg:"--ttl" help:"Maximum \"Time To Live\" (in RFC3339 (duration) eg. \"P1DT30H4S\") of this job, after which it will be automatically terminated"`
-		ParametersIni          string   `json:"parameters" arg:"-" ini:"parameters"`
-		Config                 *string  `arg:"--conf"`
-		JobConfig              *string  `arg:"--job-conf"`
-		File                   string   `json:"file" ini:"file" arg:"positional"`
-		Parameters             []string `arg:"positional"`
+		JobName                string            `json:"jobname" ini:"jobname" arg:"env:JOB_NAME" help:"Job name (can be set with ENV vars JOB_NAME)"`
+		Region                 string            `json:"region" ini:"region" arg:"env:OS_REGION" default:"GRA" help:"Openstack region of the job (can be set with ENV vars OS_REGION)"`
+		ProjectID              string            `json:"projectid" ini:"projectid" arg:"env:OS_PROJECT_ID" help:"Openstack ProjectID (can be set with ENV vars OS_PROJECT_ID)"`
+		SparkVersion           string            `json:"spark-version" ini:"spark-version" arg:"--spark-version,env:SPARK_VERSION" default:"2.4.3" help:"Version of spark (can be set with ENV vars SPARK_VERSION)"`
+		Upload                 string            `json:"upload" ini:"upload" arg:"env:UPLOAD" help:"Comma-delimited list of file path/dir to upload before running the job (can be set with ENV vars UPLOAD)"`
+		UploadInclude          string            `json:"upload-include" ini:"upload-include" arg:"--upload-include" help:"Comma-delimited list of glob patterns of the files of the uploaded directories to upload (eg. \"*.py,conf/*.json\"), all when not set"`
+		UploadExclude          string            `json:"upload-exclude" ini:"upload-exclude" arg:"--upload-exclude" help:"Comma-delimited list of glob patterns of the files and directories of the uploaded directories not to upload (eg. \"tests,*.log\")"`
+		UploadPrefix           string            `json:"upload-prefix" ini:"upload-prefix" arg:"--upload-prefix" help:"Prefix of the uploaded objects in the container (\"/\" for the container root) [default: directory of FILE]"`
+		UploadParallelism      int               `json:"upload-parallelism" ini:"upload-parallelism" arg:"--upload-parallelism" help:"Number of files uploaded concurrently [default: 4]"`
+		ForceUpload            bool              `json:"force-upload" ini:"force-upload" arg:"--force-upload" help:"Upload the files even when their content is already in the container"`
+		UploadVersion          string            `json:"upload-version" ini:"upload-version" arg:"--upload-version" help:"Upload the files under <prefix>/_versions/<version>/ and use them in the job, the version is \"hash\" for a hash of their content or any run ID"`
+		UploadKeepVersions     int               `json:"upload-keep-versions" ini:"upload-keep-versions" arg:"--upload-keep-versions" help:"With --upload-version, delete the older versions but this number of most recent ones, including the current one"`
+		CleanupUploads         bool              `json:"cleanup-uploads" ini:"cleanup-uploads" arg:"--cleanup-uploads" help:"Delete the uploaded files once the job is over"`
+		CleanupOnlyCompleted   bool              `json:"cleanup-only-completed" ini:"cleanup-only-completed" arg:"--cleanup-only-completed" help:"With --cleanup-uploads, only delete the uploaded files when the job is COMPLETED"`
+		DownloadLogs           string            `json:"download-logs" ini:"download-logs" arg:"--download-logs" help:"Download the logs of the job from the object storage into the given directory once it's over"`
+		DecompressLogs         bool              `json:"decompress-logs" ini:"decompress-logs" arg:"--decompress-logs" help:"With --download-logs, decompress the gzip compressed logs"`
+		ConcatLogs             bool              `json:"concat-logs" ini:"concat-logs" arg:"--concat-logs" help:"With --download-logs, also concatenate the driver and executor logs, decompressed, in <job id>.log"`
+		Class                  string            `json:"class" ini:"class" help:"main-class"`
+		DriverCores            string            `json:"driver-cores" ini:"driver-cores" arg:"--driver-cores"`
+		DriverMemory           string            `json:"driver-memory" ini:"driver-memory" arg:"--driver-memory" help:"Driver memory in (gigi/mebi)bytes (eg. \"10G\")"`
+		DriverMemoryOverhead   string            `json:"driver-memoryOverhead" ini:"driver-memoryOverhead" arg:"--driver-memoryOverhead" help:"Driver memoryOverhead in (gigi/mebi)bytes (eg. \"10G\")"`
+		ExecutorCores          string            `json:"executor-cores" ini:"executor-cores" arg:"--executor-cores"`
+		ExecutorNum            string            `json:"num-executors" ini:"num-executors" arg:"--num-executors"`
+		ExecutorMemory         string            `json:"executor-memory" ini:"executor-memory" arg:"--executor-memory" help:"Executor memory in (gigi/mebi)bytes (eg. \"10G\")"`
+		ExecutorMemoryOverhead string            `json:"executor-memoryOverhead" ini:"executor-memoryOverhead" arg:"--executor-memoryOverhead" help:"Executor memory in (gigi/mebi)bytes (eg. \"10G\")"`
+		Packages               string            `json:"packages" ini:"packages" arg:"--packages" help:"Comma-delimited list of Maven coordinates"`
+		Repositories           string            `json:"repositories" ini:"repositories" arg:"--repositories" help:"Comma-delimited list of additional repositories (or resolvers in SBT)"`
+		PyPackage              string            `json:"py-package" ini:"py-package" arg:"--py-package" help:"Local python package or source directory to zip, with the dependencies of its requirements.txt, and add to --py-files"`
+		PyFiles                string            `json:"py-files" ini:"py-files" arg:"--py-files" help:"Comma-delimited list of .zip, .egg or .py files to place on the PYTHONPATH of a python job"`
+		Jars                   string            `json:"jars" ini:"jars" arg:"--jars" help:"Comma-delimited list of jars to include on the driver and executor classpaths"`
+		Files                  string            `json:"files" ini:"files" arg:"--files" help:"Comma-delimited list of files to place in the working directory of each executor"`
+		PropertiesFile         string            `json:"properties-file" ini:"properties-file" arg:"--properties-file" help:"Read properties from the given file: a local spark-defaults style file is merged with the other spark properties, otherwise the name of a file in the container of the job"`
+		TTL                    string            `json:"ttl" ini:"ttl" arg:"--ttl" help:"Maximum \"Time To Live\" (in RFC3339 (duration) eg. \"P1DT30H4S\") of this job, after which it will be automatically terminated"`
+		ParametersIni          string            `json:"parameters" arg:"-" ini:"parameters"`
+		SparkConf              []string          `json:"-" ini:"-" arg:"--spark-conf,separate" help:"Spark property key=value, can be repeated (eg. --spark-conf spark.sql.shuffle.partitions=200)"`
+		SparkProperties        map[string]string `json:"spark-conf" ini:"-" arg:"-"`
+		Conf                   []string          `json:"-" ini:"-" arg:"--conf,separate" help:"Alias of --spark-conf, as in spark-submit. A value without \"=\" is the path to the configuration.ini file (deprecated, use --config)"`
+		Config                 *string           `arg:"--config" help:"Path to the configuration.ini file [default: configuration.ini]"`
+		JobConfig              *string           `arg:"--job-conf"`
+		Detach                 bool              `json:"detach" ini:"detach" arg:"--detach" help:"Submit the job and exit immediately after printing its ID"`
+		JobIDFile              string            `json:"job-id-file" ini:"job-id-file" arg:"--job-id-file" help:"With --detach, write the submitted job as JSON to the given file (\"-\" for stdout)"`
+		Output                 string            `json:"output" ini:"output" arg:"--output" help:"Output format: text or json (newline-delimited json events) [default: text]"`
+		LogFile                string            `json:"log-file" ini:"log-file" arg:"--log-file" help:"Write the log lines of the job to the given file, the console only showing the status changes and the ERROR lines, unless --log-level is set"`
+		LogFileMaxSize         *int              `json:"log-file-max-size" ini:"log-file-max-size" arg:"--log-file-max-size" help:"Size in MiB from which the log file is rotated, 0 to never rotate it [default: 100]"`
+		LogFileMaxFiles        *int              `json:"log-file-max-files" ini:"log-file-max-files" arg:"--log-file-max-files" help:"Number of rotated log files kept, 0 to keep none [default: 5]"`
+		LogFileCompress        bool              `json:"log-file-compress" ini:"log-file-compress" arg:"--log-file-compress" help:"Compress the rotated log files with gzip"`
+		LogTail                *int              `json:"log-tail" ini:"log-tail" arg:"--log-tail" help:"With --log-file, number of the last log lines printed when the job doesn't complete, 0 to print none [default: 50]"`
+		LogLevel               string            `json:"log-level" ini:"log-level" arg:"--log-level" help:"Least severe level of the printed log lines: TRACE, DEBUG, INFO, WARN, ERROR or FATAL, the lines without level having the one of the previous line"`
+		Grep                   string            `json:"grep" ini:"grep" arg:"--grep" help:"Regular expression the printed log lines must match"`
+		OnSignal               string            `json:"on-signal" ini:"on-signal" arg:"--on-signal" help:"Action on SIGINT/SIGTERM: ask (kill without TTY), kill or detach [default: ask]"`
+		DryRun                 bool              `json:"dry-run" ini:"dry-run" arg:"--dry-run" help:"Validate the job and print the request which would be sent to the API and the files which would be uploaded, without submitting nor uploading anything"`
+		File                   string            `json:"file" ini:"file" arg:"positional"`
+		Parameters             []string          `arg:"positional"`
+
+		// sparkProperties content of the properties file generated with the spark properties, uploaded with the files
+		sparkProperties string `arg:"-"`
+	}
+
+	// DryRun request and uploads printed by --dry-run
+	DryRun struct {
+		ProjectID string          `json:"projectid"`
+		Path      string          `json:"path"`
+		Payload   *JobSubmit      `json:"payload"`
+		Uploads   []*DryRunUpload `json:"uploads"`
+	}
+
+	// DryRunUpload file which would be uploaded
+	DryRunUpload struct {
+		Source    string `json:"source"`
+		Protocol  string `json:"protocol"`
+		Container string `json:"container"`
+		Object    string `json:"object"`
+	}
+
+	// GeneratedUpload file generated at submission, uploaded under the upload prefix
+	GeneratedUpload struct {
+		// Source what the file is generated from
+		Source string
+		Name   string
+	}
+
+	// DetachedJob job written by --job-id-file once submitted in detached mode
+	DetachedJob struct {
+		ID        string `json:"id"`
+		Name      string `json:"name"`
+		ProjectID string `json:"projectid"`
+		Region    string `json:"region"`
+		Status    string `json:"status"`
 	}
 )
 
+// Description of the program displayed in the help
+func (CLIArgs) Description() string {
+	return "Submit a spark job to OVHcloud Data Processing and wait for its completion.\n" +
+		"Other commands: submit, status <job-id>, logs <job-id>, kill <job-id>, list, attach <job-id> (use <command> --help for details)"
+}
+
 // main ovh-spark-submit entry point
 func main() {
+	// clean args and run the requested command, submitting a job by default
+	utils.CleanArgs()
+
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			command(os.Args[2:])
+			return
+		}
+	}
+
+	submitCommand(os.Args[1:])
+}
+
+// submitCommand submit a job and wait for its completion
+func submitCommand(arguments []string) {
 	var err error
 
-	// clean args and parse them to see if we need to process files or not
-	utils.CleanArgs()
+	// parse args to see if we need to process files or not
+	os.Args = append([]string{os.Args[0]}, arguments...)
 	parser := arg.MustParse(&args)
+	if err := args.splitConf(); err != nil {
+		parser.Fail(err.Error())
+	}
 
 	if args.Config == nil {
 		args.Config = &defaultConfigPath
 	}
 
-	conf, err := InitConf(*args.Config)
-	if err != nil {
-		log.Fatalf("Unable to load conf: %s", err)
-	}
+	conf, protocols := mustLoadConf(*args.Config)
 	if _, ok := conf["spark"]; ok {
 		err = conf["spark"].MapTo(&args)
 
 		if err != nil {
-			log.Fatalf("Unable to load conf: %s", err)
+			Fatalf(ExitCodeConfig, "Unable to load conf: %s", err)
 		}
 	}
 
@@ -102,105 +220,198 @@ func main() {
 		if _, err := os.Stat(*args.JobConfig); err == nil {
 			content, err := os.ReadFile(*args.JobConfig)
 			if err != nil {
-				log.Fatalf("Unable to load job conf: %s", err)
+				Fatalf(ExitCodeConfig, "Unable to load job conf: %s", err)
 			}
 			switch {
 			case strings.HasSuffix(*args.JobConfig, ".json"):
 				if err := json.Unmarshal(content, &fileArgs); err != nil {
-					log.Fatalf("Unable to load job conf: %s", err)
+					Fatalf(ExitCodeConfig, "Unable to load job conf: %s", err)
 				}
 			case strings.HasSuffix(*args.JobConfig, ".hjson"):
 				if err := hjson.Unmarshal(content, &fileArgs); err != nil {
-					log.Fatalf("Unable to load job conf: %s", err)
+					Fatalf(ExitCodeConfig, "Unable to load job conf: %s", err)
 				}
 			default:
-				log.Fatalf("Job configuration must be a json or hjson file and is currently: %s", *args.JobConfig)
+				Fatalf(ExitCodeConfig, "Job configuration must be a json or hjson file and is currently: %s", *args.JobConfig)
 			}
 
 		} else if !strings.HasSuffix(*args.JobConfig, ".json") && !strings.HasSuffix(*args.JobConfig, ".hjson") {
-			log.Fatalf("Job configuration must be a json or hjson file and is currently: %s", *args.JobConfig)
+			Fatalf(ExitCodeConfig, "Job configuration must be a json or hjson file and is currently: %s", *args.JobConfig)
 		}
 	}
 
 	jobSubmitValue := ParsArgs(*parser)
 
-	protocols, err := validConfig(conf, *args.Config)
-	if err != nil {
-		log.Fatalf("Invalid conf: %s", err)
+	if args.Output != "" {
+		if err := out.SetFormat(args.Output); err != nil {
+			parser.Fail(err.Error())
+		}
+	}
+	if args.JobIDFile == "-" && out.JSON() {
+		// the job would break the stream of events, the submitted event already holds it
+		parser.Fail("--job-id-file can't be \"-\" with --output json, use the submitted event")
 	}
+	logFilter, _ := NewLogFilter(ConsoleLogLevel(args.LogLevel, args.LogFile), args.Grep, out.Colored())
+	out.SetLogFilter(logFilter)
 
-	ovhConf := new(OVHConf)
-	if err := conf["ovh"].MapTo(ovhConf); err != nil {
-		log.Fatalf("Unable to parse \"ovh\" conf: %s", err)
+	if args.DryRun {
+		generated, err := args.generatedUploads()
+		if err != nil {
+			Fatalf(ExitCodeConfig, "Unable to list the generated files to upload: %s", err)
+		}
+		uploads, err := DryRunUploads(args.Upload, generated, args.File, protocols, args.UploadOptions())
+		if err != nil {
+			Fatalf(ExitCodeUpload, "Error while listing file(s) to upload: %s", err)
+		}
+		if err := PrintDryRun(os.Stdout, args.ProjectID, jobSubmitValue, uploads); err != nil {
+			Fatalf(ExitCodeError, "Unable to print dry run: %s", err)
+		}
+		return
 	}
 
-	ovhClient, err := ovh.NewClient(
-		ovhConf.Endpoint,
-		ovhConf.ApplicationKey,
-		ovhConf.ApplicationSecret,
-		ovhConf.ConsumerKey,
-	)
-	if err != nil {
-		log.Fatalf("Error while creating OVH Client: %s", err)
+	client := mustInitClient(conf, args.ProjectID)
+
+	if args.LogFile != "" {
+		logFile := NewLogFile(args.LogFile, args.LogFileMaxSize, args.LogFileMaxFiles, args.LogFileCompress, args.LogTail)
+		if err := logFile.Open(); err != nil {
+			Fatalf(ExitCodeConfig, "Unable to open the log file: %s", err)
+		}
+		out.SetLogFile(logFile)
 	}
 
-	if args.Upload != "" {
-		args.File = filepath.Clean(args.File)
-		splitFile := strings.Split(args.File, "/")
-		protocol := strings.TrimSuffix(splitFile[0], ":")
-		containerName := splitFile[1]
+	var logsDownload *LogsDownload
+	if args.DownloadLogs != "" {
+		storage, err := LogsStorage(conf, protocols)
+		if err != nil {
+			Fatalf(ExitCodeConfig, "Unable to download the logs: %s", err)
+		}
+		logsDownload = &LogsDownload{
+			Storage:    storage,
+			Dir:        args.DownloadLogs,
+			Decompress: args.DecompressLogs,
+			Concat:     args.ConcatLogs,
+		}
+	}
+
+	var cleanup *Cleanup
+	if args.Upload != "" || args.PyPackage != "" || args.sparkProperties != "" {
+		// the file has been validated by ParsArgs
+		protocol, containerName, _, _ := ParseFilePath(args.File)
 		if inTheList(protocol, protocols) {
 			storage, err := upload.New(conf[protocol], protocol)
 			if err != nil {
-				log.Fatalf("Error while initializing upload storage configurations: %s", err)
+				Fatalf(ExitCodeUpload, "Error while initializing upload storage configurations: %s", err)
 			}
 
 			if storage == nil {
-				log.Fatalf("No configuration found for protocol %s", protocol)
+				Fatalf(ExitCodeUpload, "No configuration found for protocol %s", protocol)
+			}
+			generatedDir, err := args.writeGeneratedUploads()
+			if err != nil {
+				Fatalf(ExitCodeConfig, "Unable to generate the files to upload: %s", err)
+			}
+			uploaded, err := storage.Upload(splitList(args.Upload), containerName, args.UploadOptions())
+			if generatedDir != "" {
+				removeDir(generatedDir)
 			}
-			filesList := strings.Split(args.Upload, ",")
-			for _, file := range filesList {
-				err = storage.Upload(file, containerName)
+			if args.CleanupUploads {
+				cleanup = &Cleanup{
+					Storage:       storage,
+					Container:     containerName,
+					Objects:       args.cleanupObjects(uploaded),
+					OnlyCompleted: args.CleanupOnlyCompleted,
+				}
+			}
+			if err != nil {
+				cleanup.Abort()
+				Fatalf(ExitCodeUpload, "Error while uploading file(s): %s", err)
+			}
+
+			if args.UploadVersion != "" {
+				// date the use of the version, as its unchanged files weren't uploaded again
+				if err := upload.MarkVersion(storage, containerName, args.uploadPrefix(), args.UploadVersion); err != nil {
+					log.Printf("Unable to mark the version %s of the uploaded files as used: %s", args.UploadVersion, err)
+				}
+			}
+			if args.UploadVersion != "" && args.UploadKeepVersions > 0 {
+				deleted, err := upload.CleanVersions(storage, containerName, args.uploadPrefix(), args.UploadVersion, args.UploadKeepVersions)
 				if err != nil {
-					log.Fatalf("Error while uploading file(s): %s", err)
+					log.Printf("Unable to delete the old versions of the uploaded files: %s", err)
+				}
+				for _, version := range deleted {
+					log.Printf("Version %s of the uploaded files deleted", version)
 				}
 			}
 		} else {
-			log.Fatalf("Error while initializing upload storage configurations: protocol %s isn't configured in %s or isn't supported", protocol, *args.Config)
+			Fatalf(ExitCodeUpload, "Error while initializing upload storage configurations: protocol %s isn't configured in %s or isn't supported", protocol, *args.Config)
 		}
 	}
 
-	client := &Client{
-		OVH: ovhClient,
-	}
-
 	job, err := client.Submit(args.ProjectID, jobSubmitValue)
 	if err != nil {
+		cleanup.Abort()
 		if ovherr, ok := err.(*ovh.APIError); ok {
+			exitCode := ExitCodeAPI
+			if ovherr.Code == http.StatusBadRequest || ovherr.Code == http.StatusUnprocessableEntity {
+				exitCode = ExitCodeSubmitRejected
+			}
 			if err.Error() == "Error 422: \"Unprocessable Entity\"" {
-				log.Fatalf("Unable to submit job: %s :: %s :: %v. "+
+				Fatalf(exitCode, "Unable to submit job: %s :: %s :: %v. "+
 					"Please check that your requested job complies with the OVHcloud Data Processing capabilities "+
 					"(https://docs.ovh.com/gb/en/data-processing/capabilities/#the-apache-spark-job-in-data-processing-is-limited-to)", err, ovherr.Class, GetErrorDetails(ovherr))
 			} else {
-				log.Fatalf("Unable to submit job: %s :: %s :: %v.", err, ovherr.Class, GetErrorDetails(ovherr))
+				Fatalf(exitCode, "Unable to submit job: %s :: %s :: %v.", err, ovherr.Class, GetErrorDetails(ovherr))
 			}
 		}
 
-		log.Fatalf("Unable to submit job: %s", err)
+		Fatalf(ExitCodeAPI, "Unable to submit job: %s", err)
 	}
 
 	client.JobID = job.ID
-	log.Printf("Job '%s' submitted with id %s", job.Name, job.ID)
+	out.Submitted(job)
 
+	if args.Detach {
+		// the submitted event already holds the job ID in json output
+		if args.JobIDFile == "" && out.JSON() {
+			return
+		}
+		if err := WriteDetachedJob(args.ProjectID, job, args.JobIDFile); err != nil {
+			Fatalf(ExitCodeError, "Unable to write job id: %s", err)
+		}
+		return
+	}
+
+	Wait(client, job, args.OnSignal, cleanup, logsDownload)
+}
+
+// Wait follow the job until it ends, download its logs, diagnose its failure and run the cleanup of its uploaded
+// files if any, and exit with its return code
+func Wait(client *Client, job *JobStatus, onSignal string, cleanup *Cleanup, logsDownload *LogsDownload) {
 	returnCodeChan := make(chan int)
 
 	go func() {
-		job := Loop(client, job)
-		log.Printf("Job status is : %s", job.Status)
-		if job.Status == "COMPLETED" {
-			log.Printf("Job exit code : %v", job.ReturnCode)
+		job, err := Loop(client, job, onSignal)
+		switch {
+		case errors.Is(err, ErrDetached):
+			log.Printf("Job %s is still %s, you can follow it with the attach command", job.ID, job.Status)
+			returnCodeChan <- 0
+			return
+		case err != nil && !errors.Is(err, ErrJobKilled):
+			Fatalf(ExitCodeAPI, "Unable to follow job: %s", err)
 		}
-		returnCodeChan <- int(job.ReturnCode)
+
+		out.Job(job)
+		out.CloseLogFile(job)
+		files := logsDownload.Run(client, job)
+		if job.Status == JobStatusFAILED {
+			out.Diagnosis(job, files)
+		}
+		cleanup.Run(job)
+		if errors.Is(err, ErrJobKilled) {
+			returnCodeChan <- ExitCodeKilled
+			return
+		}
+		returnCodeChan <- ExitCode(job)
 	}()
 
 	// return the channel to a value, and get the defer close channel
@@ -209,6 +420,268 @@ func main() {
 	os.Exit(returnedExitCode)
 }
 
+// DryRunUploads list the files which would be uploaded to the storage of the job file, the generated ones
+// included without being generated
+func DryRunUploads(uploads string, generated []*GeneratedUpload, file string, protocols []string, options *upload.Options) ([]*DryRunUpload, error) {
+	dryRunUploads := []*DryRunUpload{}
+	if uploads == "" && len(generated) == 0 {
+		return dryRunUploads, nil
+	}
+	if options == nil {
+		options = &upload.Options{}
+	}
+
+	protocol, containerName, _, err := ParseFilePath(file)
+	if err != nil {
+		return nil, err
+	}
+	if !inTheList(protocol, protocols) {
+		return nil, fmt.Errorf("protocol %s isn't configured or isn't supported", protocol)
+	}
+
+	for _, source := range splitList(uploads) {
+		files, err := upload.Files(source, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			dryRunUploads = append(dryRunUploads, &DryRunUpload{
+				Source:    f.Path,
+				Protocol:  protocol,
+				Container: containerName,
+				Object:    f.Object,
+			})
+		}
+	}
+
+	for _, g := range generated {
+		dryRunUploads = append(dryRunUploads, &DryRunUpload{
+			Source:    g.Source,
+			Protocol:  protocol,
+			Container: containerName,
+			Object:    path.Join(options.Prefix, g.Name),
+		})
+	}
+	return dryRunUploads, nil
+}
+
+// generatedUploads files generated at submission and uploaded with the --upload files:
+// the zip of the python package and the properties file of the spark properties
+func (a *CLIArgs) generatedUploads() ([]*GeneratedUpload, error) {
+	var generated []*GeneratedUpload
+	if a.PyPackage != "" {
+		zipName, err := PythonPackageZip(a.PyPackage)
+		if err != nil {
+			return nil, err
+		}
+		generated = append(generated, &GeneratedUpload{Source: a.PyPackage, Name: zipName})
+	}
+	if a.sparkProperties != "" {
+		generated = append(generated, &GeneratedUpload{Source: "spark properties", Name: PropertiesFileName(a.sparkProperties)})
+	}
+	return generated, nil
+}
+
+// writeGeneratedUploads generate the files to upload in a temporary directory and add them to the files to upload.
+// It returns the temporary directory, to remove once uploaded, empty when there's no file to generate
+func (a *CLIArgs) writeGeneratedUploads() (string, error) {
+	if a.PyPackage == "" && a.sparkProperties == "" {
+		return "", nil
+	}
+	dir, err := os.MkdirTemp("", "ovh-spark-submit")
+	if err != nil {
+		return "", err
+	}
+
+	var files []string
+	if a.PyPackage != "" {
+		zipPath, err := PackagePython(a.PyPackage, dir)
+		if err != nil {
+			removeDir(dir)
+			return "", fmt.Errorf("unable to package %s: %w", a.PyPackage, err)
+		}
+		files = append(files, zipPath)
+	}
+	if a.sparkProperties != "" {
+		propertiesPath := filepath.Join(dir, PropertiesFileName(a.sparkProperties))
+		if err := os.WriteFile(propertiesPath, []byte(a.sparkProperties), 0600); err != nil {
+			removeDir(dir)
+			return "", err
+		}
+		files = append(files, propertiesPath)
+	}
+	a.addUploads(files)
+	return dir, nil
+}
+
+// removeDir delete a temporary directory, errors are only logged
+func removeDir(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		log.Printf("Unable to delete %s: %s", dir, err)
+	}
+}
+
+// cleanupObjects uploaded objects to delete once the job is over. The generated properties file is kept:
+// named after its content, it's shared with the other runs with the same spark properties
+func (a *CLIArgs) cleanupObjects(uploaded []string) []string {
+	if a.sparkProperties == "" {
+		return uploaded
+	}
+
+	shared := path.Join(a.UploadOptions().Prefix, PropertiesFileName(a.sparkProperties))
+	objects := make([]string, 0, len(uploaded))
+	for _, object := range uploaded {
+		if object != shared {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
+
+// UploadOptions options of the upload of the --upload files, under the prefix of their version if any
+func (a *CLIArgs) UploadOptions() *upload.Options {
+	prefix := a.uploadPrefix()
+	if a.UploadVersion != "" {
+		prefix = upload.VersionPrefix(prefix, a.UploadVersion)
+	}
+
+	return &upload.Options{
+		Include:     splitList(a.UploadInclude),
+		Exclude:     splitList(a.UploadExclude),
+		Prefix:      prefix,
+		Parallelism: a.UploadParallelism,
+		Force:       a.ForceUpload,
+	}
+}
+
+// uploadPrefix prefix of the uploaded files. Unless --upload-prefix is given, files are uploaded
+// in the directory of the job file, so that it matches the uploaded main file
+func (a *CLIArgs) uploadPrefix() string {
+	if a.UploadPrefix != "" {
+		return strings.Trim(a.UploadPrefix, "/")
+	}
+
+	if _, _, object, err := ParseFilePath(a.File); err == nil && path.Dir(object) != "." {
+		return path.Dir(object)
+	}
+	return ""
+}
+
+// mainObject name of the main file in the job: the uploaded one when the job file is among the files of --upload,
+// under the upload prefix and version, otherwise the object of the job file itself. A job file outside of
+// --upload-prefix matches the upload of its base name, which lands under the prefix
+func (a *CLIArgs) mainObject(object string) (string, error) {
+	prefix := a.uploadPrefix()
+	name := path.Base(object)
+	if prefix != "" && strings.HasPrefix(object, prefix+"/") {
+		name = strings.TrimPrefix(object, prefix+"/")
+	}
+
+	options := a.UploadOptions()
+	uploaded := path.Join(options.Prefix, name)
+	for _, source := range splitList(a.Upload) {
+		files, err := upload.Files(source, options)
+		if err != nil {
+			return "", err
+		}
+		for _, file := range files {
+			if file.Object == uploaded {
+				return uploaded, nil
+			}
+		}
+	}
+	return object, nil
+}
+
+// splitList split a comma-delimited list, ignoring the empty values
+func splitList(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// PrintDryRun print the request which would be sent to the API and the files which would be uploaded
+func PrintDryRun(w io.Writer, projectID string, jobSubmit *JobSubmit, uploads []*DryRunUpload) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(&DryRun{
+		ProjectID: projectID,
+		Path:      fmt.Sprintf(DataProcessingSubmit, url.QueryEscape(projectID)),
+		Payload:   jobSubmit,
+		Uploads:   uploads,
+	})
+}
+
+// WriteDetachedJob print the ID of the job submitted in detached mode,
+// or write it as JSON into jobIDFile ("-" for stdout) when given
+func WriteDetachedJob(projectID string, job *JobStatus, jobIDFile string) error {
+	if jobIDFile == "" {
+		fmt.Println(job.ID)
+		return nil
+	}
+
+	content, err := json.Marshal(&DetachedJob{
+		ID:        job.ID,
+		Name:      job.Name,
+		ProjectID: projectID,
+		Region:    job.Region,
+		Status:    job.Status,
+	})
+	if err != nil {
+		return err
+	}
+	content = append(content, '\n')
+
+	if jobIDFile == "-" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return os.WriteFile(jobIDFile, content, 0o644)
+}
+
+// mustLoadConf load and validate configuration.ini, returning its sections and the storage protocols configured
+func mustLoadConf(confPath string) (map[string]*ini.Section, []string) {
+	conf, err := InitConf(confPath)
+	if err != nil {
+		Fatalf(ExitCodeConfig, "Unable to load conf: %s", err)
+	}
+
+	protocols, err := validConfig(conf, confPath)
+	if err != nil {
+		Fatalf(ExitCodeConfig, "Invalid conf: %s", err)
+	}
+
+	return conf, protocols
+}
+
+// mustInitClient create the OVH API client of the project from the [ovh] configuration
+func mustInitClient(conf map[string]*ini.Section, projectID string) *Client {
+	ovhConf := new(OVHConf)
+	if err := conf[OVHConfig].MapTo(ovhConf); err != nil {
+		Fatalf(ExitCodeConfig, "Unable to parse \"ovh\" conf: %s", err)
+	}
+
+	ovhClient, err := ovh.NewClient(
+		ovhConf.Endpoint,
+		ovhConf.ApplicationKey,
+		ovhConf.ApplicationSecret,
+		ovhConf.ConsumerKey,
+	)
+	if err != nil {
+		Fatalf(ExitCodeConfig, "Error while creating OVH Client: %s", err)
+	}
+
+	return &Client{
+		OVH:       ovhClient,
+		ProjectID: projectID,
+		Retry:     NewRetryPolicy(ovhConf.RetryMaxAttempts, ovhConf.RetryBackoff, ovhConf.RetryMaxBackoff, ovhConf.RetryJitter),
+	}
+}
+
 // initConf init configuration.ini file
 func InitConf(confPath string) (map[string]*ini.Section, error) {
 	cfg, err := ini.Load(confPath)
@@ -232,7 +705,7 @@ func ParsArgs(p arg.Parser) *JobSubmit {
 	err := mergo.Merge(&args, fileArgs)
 
 	if err != nil {
-		log.Fatalf("Error while initializing configurations: %s", err)
+		Fatalf(ExitCodeConfig, "Error while initializing configurations: %s", err)
 	}
 
 	jobSubmit := &JobSubmit{
@@ -248,6 +721,17 @@ func ParsArgs(p arg.Parser) *JobSubmit {
 		jobSubmit.Name = randomdata.SillyName()
 	}
 
+	// a local properties file is inlined, its resource properties are used for the flags which haven't been given
+	var properties map[string]string
+	localPropertiesFile := args.PropertiesFile != "" && IsLocalPropertiesFile(args.PropertiesFile)
+	if localPropertiesFile {
+		properties, err = LoadPropertiesFile(args.PropertiesFile)
+		if err != nil {
+			p.Fail(fmt.Sprintf("Invalid value for --properties-file: %s", err))
+		}
+		properties = args.applyProperties(properties)
+	}
+
 	if args.ProjectID == "" {
 		p.Fail("--projectid is required")
 	}
@@ -289,13 +773,90 @@ func ParsArgs(p arg.Parser) *JobSubmit {
 			Value: JobTypePython,
 		})
 	}
-	args.File = filepath.Clean(args.File)
-	splitFile := strings.Split(args.File, "/")
-	jobSubmit.ContainerName = splitFile[1]
+	_, containerName, objectName, err := ParseFilePath(args.File)
+	if err != nil {
+		Fatalf(ExitCodeConfig, "Invalid file: %s", err)
+	}
+	jobSubmit.ContainerName = containerName
+
+	if (args.PyFiles != "" || args.PyPackage != "") && strings.EqualFold(filepath.Ext(args.File), ".jar") {
+		p.Fail("--py-files and --py-package can only be used with a python job")
+	}
+
+	// the python package is zipped once the arguments are validated, not to run pip on a dry run, see writeGeneratedUploads
+	pyPackageZip := ""
+	if args.PyPackage != "" {
+		pyPackageZip, err = PythonPackageZip(args.PyPackage)
+		if err != nil {
+			p.Fail(fmt.Sprintf("Invalid value for --py-package: %s", err))
+		}
+	}
+
+	dependencies := []struct {
+		flag       string
+		list       string
+		extensions []string
+		property   string
+	}{
+		{"--py-files", args.PyFiles, PyFilesExtensions, "spark.submit.pyFiles"},
+		{"--jars", args.Jars, JarsExtensions, "spark.jars"},
+		{"--files", args.Files, nil, "spark.files"},
+	}
+	if strings.ContainsAny(args.UploadVersion, "/\\") || args.UploadVersion == "." || args.UploadVersion == ".." {
+		p.Fail("Invalid value for --upload-version. It must be \"hash\" or a run ID without \"/\"")
+	}
+	if args.UploadVersion == UploadVersionHash && args.CleanupUploads {
+		p.Fail("--cleanup-uploads can't be used with --upload-version hash, the files of a version are shared with the other runs")
+	}
+	if args.UploadVersion == UploadVersionHash {
+		// hash of all the files to upload, local dependencies included
+		sources := splitList(args.Upload)
+		if args.PyPackage != "" {
+			// the sources of the python package, its zip isn't built yet
+			sources = append(sources, args.PyPackage)
+		}
+		for _, dependency := range dependencies {
+			sources = append(sources, LocalDependencies(dependency.list)...)
+		}
+		options := args.UploadOptions()
+		options.Prefix = ""
+		version, err := upload.Hash(sources, options)
+		if err != nil {
+			Fatalf(ExitCodeUpload, "Unable to hash the files to upload: %s", err)
+		}
+		args.UploadVersion = version
+	}
+
+	// the dependencies are spark properties, sent in the generated properties file
+	dependencyConf := map[string]string{}
+	for _, dependency := range dependencies {
+		var packaged []string
+		if dependency.flag == "--py-files" && pyPackageZip != "" {
+			packaged = []string{path.Join(args.UploadOptions().Prefix, pyPackageZip)}
+		}
+		if dependency.list == "" && len(packaged) == 0 {
+			continue
+		}
+		local, objects, err := Dependencies(dependency.list, containerName, args.UploadOptions().Prefix, dependency.extensions)
+		if err != nil {
+			p.Fail(fmt.Sprintf("Invalid value for %s: %s", dependency.flag, err))
+		}
+		objects = append(packaged, objects...)
+		// local dependencies are uploaded with the files of --upload
+		args.addUploads(local)
+		dependencyConf[dependency.property] = strings.Join(objects, ",")
+	}
+
+	if args.Upload != "" {
+		objectName, err = args.mainObject(objectName)
+		if err != nil {
+			Fatalf(ExitCodeUpload, "Unable to list the files to upload: %s", err)
+		}
+	}
 
 	jobSubmit.EngineParameters = append(jobSubmit.EngineParameters, &JobEngineParameter{
 		Name:  ParameterMainCode,
-		Value: strings.Join(splitFile[2:], "/"),
+		Value: objectName,
 	})
 
 	value, err := utils.ParseSize(args.DriverMemory)
@@ -363,6 +924,47 @@ func ParsArgs(p arg.Parser) *JobSubmit {
 		})
 	}
 
+	if args.CleanupUploads && args.Detach {
+		p.Fail("--cleanup-uploads can't be used with --detach, the job isn't followed until it's over")
+	}
+
+	if args.DownloadLogs != "" && args.Detach {
+		p.Fail("--download-logs can't be used with --detach, the job isn't followed until it's over")
+	}
+
+	if args.LogFile != "" && args.Detach {
+		p.Fail("--log-file can't be used with --detach, the job isn't followed until it's over")
+	}
+
+	if args.DownloadLogs == "" && (args.DecompressLogs || args.ConcatLogs) {
+		p.Fail("--decompress-logs and --concat-logs can only be used with --download-logs")
+	}
+
+	if args.UploadKeepVersions < 0 {
+		p.Fail("Invalid value for --upload-keep-versions. It must be positive")
+	}
+
+	if args.UploadParallelism < 0 {
+		p.Fail("Invalid value for --upload-parallelism. It must be positive")
+	}
+
+	uploadOptions := args.UploadOptions()
+	if err := upload.ValidPatterns(append(uploadOptions.Include, uploadOptions.Exclude...)); err != nil {
+		p.Fail(fmt.Sprintf("Invalid value for --upload-include/--upload-exclude: %s", err))
+	}
+
+	if _, err := NewLogFilter(args.LogLevel, args.Grep, false); err != nil {
+		p.Fail(err.Error())
+	}
+
+	if !ValidLogFileSettings(args.LogFileMaxSize, args.LogFileMaxFiles, args.LogTail) {
+		p.Fail("Invalid value for --log-file-max-size, --log-file-max-files or --log-tail. It must be positive")
+	}
+
+	if args.OnSignal != "" && !inTheList(args.OnSignal, OnSignalPolicies) {
+		p.Fail("Invalid value for --on-signal. It must be one of " + strings.Join(OnSignalPolicies, ", "))
+	}
+
 	if args.TTL != "" {
 		_, err := duration.Parse(args.TTL)
 		if err != nil {
@@ -390,60 +992,109 @@ func ParsArgs(p arg.Parser) *JobSubmit {
 		Value: strings.Join(args.Parameters, ", "),
 	})
 
-	if args.PropertiesFile != "" {
+	sparkConf, err := ParseSparkConf(args.SparkConf)
+	if err != nil {
+		p.Fail(fmt.Sprintf("Invalid value for --spark-conf: %s", err))
+	}
+	for key, value := range args.SparkProperties {
+		if err := ValidSparkConfKey(key); err != nil {
+			p.Fail(fmt.Sprintf("Invalid spark-conf in job configuration: %s", err))
+		}
+		if _, ok := sparkConf[key]; !ok {
+			sparkConf[key] = value
+		}
+	}
+	for key, value := range properties {
+		if _, ok := sparkConf[key]; !ok {
+			sparkConf[key] = value
+		}
+	}
+	// set by their dedicated flags, which take precedence over the properties file
+	for key, value := range dependencyConf {
+		sparkConf[key] = value
+	}
+
+	// the API only takes arbitrary spark properties from a properties file in the container of the job:
+	// they are written to a generated one, uploaded with the files
+	propertiesFile := ""
+	switch {
+	case len(sparkConf) > 0 && args.PropertiesFile != "" && !localPropertiesFile:
+		p.Fail("--conf/--spark-conf, the spark-conf of the job configuration and the dependencies (--py-files, --py-package, --jars, --files) " +
+			"can't be used with a --properties-file already in the container, use a local one")
+	case len(sparkConf) > 0:
+		args.sparkProperties = FormatProperties(sparkConf)
+		propertiesFile = path.Join(args.UploadOptions().Prefix, PropertiesFileName(args.sparkProperties))
+	case args.PropertiesFile != "" && !localPropertiesFile:
+		propertiesFile = args.PropertiesFile
+	}
+	if propertiesFile != "" {
 		jobSubmit.EngineParameters = append(jobSubmit.EngineParameters, &JobEngineParameter{
 			Name:  ParameterPropertiesFile,
-			Value: args.PropertiesFile,
+			Value: propertiesFile,
 		})
 	}
 
 	return jobSubmit
 }
 
-// poll Status
-func Loop(c *Client, job *JobStatus) *JobStatus {
+// ParseFilePath split a job file "protocol://container/path/to/object" into its protocol, container and object name
+func ParseFilePath(file string) (protocol, container, object string, err error) {
+	cleanFile := filepath.ToSlash(filepath.Clean(file))
+	index := strings.Index(cleanFile, ":/")
+	if index <= 0 {
+		return "", "", "", fmt.Errorf("%s must be formatted as protocol://container/path/to/file", file)
+	}
+	protocol = cleanFile[:index]
+
+	splitPath := strings.SplitN(strings.TrimLeft(cleanFile[index+2:], "/"), "/", 2)
+	if len(splitPath) != 2 || splitPath[0] == "" || splitPath[1] == "" {
+		return "", "", "", fmt.Errorf("%s must be formatted as protocol://container/path/to/file", file)
+	}
+
+	return protocol, splitPath[0], splitPath[1], nil
+}
+
+// poll Status, until the job ends or a signal is received.
+// Depending on the onSignal policy, it then returns ErrJobKilled once the job is killed or ErrDetached
+func Loop(c *Client, job *JobStatus, onSignal string) (*JobStatus, error) {
 	sigs := make(chan os.Signal, 2)
 	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
-	var err error
+	defer signal.Stop(sigs)
 statusLoop:
 	for {
 		select {
 		case <-sigs:
-			var s string
-			fmt.Printf("Do you want to kill the Job (y/N): ")
-			_, err := fmt.Scan(&s)
-			if err != nil {
-				log.Printf("Job not killed")
-			}
-
-			s = strings.TrimSpace(s)
-			s = strings.ToLower(s)
-
-			if s == "y" || s == "yes" {
-				if err := c.Kill(args.ProjectID, c.JobID); err != nil {
-					log.Printf("Job not killed: %d", err)
+			switch SignalPolicy(onSignal, utils.IsTerminal(os.Stdin)) {
+			case OnSignalDetach:
+				return job, ErrDetached
+			case OnSignalAsk:
+				if !askKill() {
+					log.Printf("Job not killed")
+					return job, ErrDetached
 				}
-				log.Printf("Job killed")
-			} else {
-				log.Printf("Job not killed")
 			}
-			return job
+			return KillAndWait(c, job, sigs)
 		case <-time.After(LoopWaitSecond * time.Second):
-			job, err = c.GetStatus(args.ProjectID, job.ID)
+			status, err := c.GetStatus(c.ProjectID, job.ID)
 			if err != nil {
+				if !IsRetryable(err) {
+					return job, fmt.Errorf("unable to retrieve status for job: %w", err)
+				}
 				log.Printf("Unable to retrieve status for job: %s", err)
 				break
 			}
+			job = status
+			out.Status(job)
 			switch job.Status {
 			case JobStatusUNKNOWN, JobStatusSUBMITTED, JobStatusPENDING:
-				log.Printf("Job is %s", job.Status)
+				// waiting for the job to run
 
 			case JobStatusCANCELLING, JobStatusTERMINATED, JobStatusFAILED, JobStatusCOMPLETED:
 				break statusLoop
 
 			case JobStatusRUNNING:
-				if jobLog, err := c.GetLogLast(args.ProjectID, job.ID); err == nil {
-					c.lastPrintLog = PrintLog(jobLog.Logs)
+				if jobLog, err := c.GetLogLast(c.ProjectID, job.ID); err == nil {
+					PrintLog(job.ID, jobLog.Logs)
 				} else {
 					log.Printf("Unable fetch job log: %s", err)
 				}
@@ -454,37 +1105,98 @@ statusLoop:
 	}
 
 	// print last logs
-	retry := true
-	for retry {
-		if jobLog, err := c.GetLogLast(args.ProjectID, job.ID); err == nil {
-			switch {
-			case jobLog.LogsAddress != "":
-				log.Printf("You can download your logs at %s", jobLog.LogsAddress)
-				retry = false
+	if err := PrintLogPages(c, job.ID, LoopWaitSecond*time.Second); err != nil {
+		log.Printf("Unable fetch job log: %s", err)
+	}
+	return job, nil
+}
 
-			case len(jobLog.Logs) > 0:
-				c.lastPrintLog = PrintLog(jobLog.Logs)
-				time.Sleep(LoopWaitSecond * time.Second)
-			default:
-				retry = false
-			}
+// PrintLogPages print the pages of logs of the job until one brings no new line or has the logs address,
+// kept in the client, waiting between the pages for the late logs
+func PrintLogPages(c *Client, jobID string, wait time.Duration) error {
+	for {
+		jobLog, err := c.GetLogLast(c.ProjectID, jobID)
+		if err != nil {
+			return err
 		}
 
+		// the logs of the page are printed before its logs address, which ends the logs
+		PrintLog(jobID, jobLog.Logs)
+		switch {
+		case jobLog.LogsAddress != "":
+			c.LogsAddress = jobLog.LogsAddress
+			out.LogsAddress(jobID, jobLog.LogsAddress)
+			return nil
+		case len(jobLog.Logs) == 0:
+			return nil
+		}
+		time.Sleep(wait)
 	}
-	return job
 }
 
-// PrintLog Print Log and return last Print Log id
-func PrintLog(jobLog []*Log) (lastPrintLog uint64) {
-	for _, jLog := range jobLog {
-		// don't print log already printed
-		if lastPrintLog >= jLog.ID {
-			continue
+// SignalPolicy policy to apply on a signal, asking being only possible with a TTY
+func SignalPolicy(onSignal string, tty bool) string {
+	switch {
+	case onSignal == "" || onSignal == OnSignalAsk:
+		if tty {
+			return OnSignalAsk
 		}
-		fmt.Println(jLog.Content)
-		lastPrintLog = jLog.ID
+		return OnSignalKill
+	default:
+		return onSignal
+	}
+}
+
+// askKill ask whether the job must be killed
+func askKill() bool {
+	var s string
+	fmt.Fprint(os.Stderr, "Do you want to kill the Job (y/N): ")
+	if _, err := fmt.Scan(&s); err != nil {
+		return false
+	}
+
+	s = strings.TrimSpace(s)
+	s = strings.ToLower(s)
+	return s == "y" || s == "yes"
+}
+
+// KillAndWait kill the job and wait for it to be cancelled, returning ErrJobKilled.
+// Another signal received while waiting exits right away with ExitCodeKilled
+func KillAndWait(c *Client, job *JobStatus, sigs <-chan os.Signal) (*JobStatus, error) {
+	if err := c.Kill(c.ProjectID, c.JobID); err != nil {
+		return job, fmt.Errorf("job not killed: %w", err)
+	}
+	log.Printf("Job killed, waiting for its cancellation")
+
+	deadline := time.Now().Add(KillWaitSecond * time.Second)
+	for time.Now().Before(deadline) {
+		status, err := c.GetStatus(c.ProjectID, c.JobID)
+		if err != nil {
+			log.Printf("Unable to retrieve status for job: %s", err)
+		} else {
+			job = status
+			out.Status(job)
+			switch job.Status {
+			case JobStatusCANCELLING, JobStatusTERMINATED, JobStatusFAILED, JobStatusCOMPLETED:
+				return job, ErrJobKilled
+			}
+		}
+		select {
+		case <-sigs:
+			Fatalf(ExitCodeKilled, "Job %s killed, not waiting for its cancellation", job.ID)
+		case <-time.After(LoopWaitSecond * time.Second):
+		}
+	}
+
+	log.Printf("Job %s is still %s after %ds", job.ID, job.Status, KillWaitSecond)
+	return job, ErrJobKilled
+}
+
+// PrintLog print the logs, GetLogLast having already sorted and deduplicated them
+func PrintLog(jobID string, jobLog []*Log) {
+	for _, jLog := range jobLog {
+		out.Log(jobID, jLog)
 	}
-	return lastPrintLog
 }
 
 // test if a value is in the given list
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"data-processing-spark-submit/upload"
 	"data-processing-spark-submit/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	arg "github.com/alexflint/go-arg"
+	"github.com/ovh/go-ovh/ovh"
 )
 
 func TestParsArgsJava(t *testing.T) {
@@ -196,6 +206,131 @@ func TestParsArgs3(t *testing.T) {
 
 }
 
+func TestParsArgsSparkConf(t *testing.T) {
+	// These are the args you would pass in on the command line
+	os.Setenv("OS_PROJECT_ID", "1377b21260f05b410e4652445ac7c95b")
+	os.Args = strings.Split("./ovh-spark-submit --driver-cores 1 --driver-memory 4G --executor-cores 1 --executor-memory 1G --num-executors 1 --conf spark.sql.shuffle.partitions=200 --spark-conf spark.eventLog.enabled=false s3://odp/test/main.py 1000", " ")
+	utils.CleanArgs()
+	args = CLIArgs{}
+	fileArgs = CLIArgs{SparkProperties: map[string]string{"spark.eventLog.enabled": "true", "spark.task.maxFailures": "8"}}
+	defer func() {
+		args = CLIArgs{}
+		fileArgs = CLIArgs{}
+	}()
+	parser := arg.MustParse(&args)
+	if err := args.splitConf(); err != nil {
+		t.Fatal(err)
+	}
+
+	if args.Config != nil {
+		t.Error("--conf key=value must not set the configuration.ini path")
+	}
+
+	job := ParsArgs(*parser)
+
+	// sorted by key, --spark-conf override the job configuration
+	expected := "spark.eventLog.enabled false\nspark.sql.shuffle.partitions 200\nspark.task.maxFailures 8\n"
+	if args.sparkProperties != expected {
+		t.Errorf("unexpected spark properties %q", args.sparkProperties)
+	}
+
+	// sent in a properties file uploaded next to the job file
+	var propertiesFile string
+	for _, params := range job.EngineParameters {
+		if params.Name == ParameterPropertiesFile {
+			propertiesFile = params.Value
+		}
+	}
+	if propertiesFile != "test/"+PropertiesFileName(expected) {
+		t.Errorf("unexpected properties file %s", propertiesFile)
+	}
+
+	dir, err := args.writeGeneratedUploads()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	written := filepath.Join(dir, PropertiesFileName(expected))
+	if content, err := os.ReadFile(written); err != nil || string(content) != expected || args.Upload != written {
+		t.Errorf("unexpected properties file %q uploaded with %s: %v", content, args.Upload, err)
+	}
+}
+
+func TestParsArgsLocalPropertiesFile(t *testing.T) {
+	// These are the args you would pass in on the command line
+	os.Setenv("OS_PROJECT_ID", "1377b21260f05b410e4652445ac7c95b")
+	os.Args = strings.Split("./ovh-spark-submit --driver-cores 1 --driver-memory 4G --executor-cores 1 --properties-file testdata/spark-defaults.conf --spark-conf spark.eventLog.enabled=false s3://odp/test/main.py 1000", " ")
+	utils.CleanArgs()
+	args = CLIArgs{}
+	defer func() {
+		args = CLIArgs{}
+	}()
+	parser := arg.MustParse(&args)
+
+	job := ParsArgs(*parser)
+
+	for _, params := range job.EngineParameters {
+		switch params.Name {
+		case ParameterPropertiesFile:
+			// the local properties file isn't sent as is
+			if params.Value != "test/"+PropertiesFileName(args.sparkProperties) {
+				t.Errorf("unexpected properties file %s", params.Value)
+			}
+		case ParameterDriverMemory:
+			// --driver-memory takes precedence
+			if params.Value != "4096" {
+				t.Fail()
+			}
+		case ParameterExecutorMemory:
+			if params.Value != "2048" {
+				t.Fail()
+			}
+		case ParameterExecutorNumber:
+			if params.Value != "4" {
+				t.Fail()
+			}
+		}
+	}
+
+	// only the properties without dedicated flag
+	if args.sparkProperties != "spark.eventLog.enabled false\nspark.serializer org.apache.spark.serializer.KryoSerializer\n" {
+		t.Errorf("unexpected spark properties %q", args.sparkProperties)
+	}
+}
+
+func TestParsArgsDependencies(t *testing.T) {
+	// These are the args you would pass in on the command line
+	os.Setenv("OS_PROJECT_ID", "1377b21260f05b410e4652445ac7c95b")
+	os.Args = strings.Split("./ovh-spark-submit --driver-cores 1 --driver-memory 4G --executor-cores 1 --executor-memory 1G --num-executors 1 --upload testdata/py.py --py-files testdata/py2.py,s3://odp/libs/helpers.zip --jars testdata/jar.jar --files s3://odp/data/lookup.csv s3://odp/py.py 1000", " ")
+	utils.CleanArgs()
+	args = CLIArgs{}
+	defer func() {
+		args = CLIArgs{}
+	}()
+	parser := arg.MustParse(&args)
+
+	job := ParsArgs(*parser)
+
+	parameters := map[string]string{}
+	for _, params := range job.EngineParameters {
+		parameters[params.Name] = params.Value
+	}
+
+	// sent as spark properties in the generated properties file
+	expected := "spark.files data/lookup.csv\nspark.jars jar.jar\nspark.submit.pyFiles py2.py,libs/helpers.zip\n"
+	if args.sparkProperties != expected {
+		t.Errorf("unexpected spark properties %q", args.sparkProperties)
+	}
+	if parameters[ParameterPropertiesFile] != PropertiesFileName(expected) {
+		t.Errorf("unexpected properties file %s", parameters[ParameterPropertiesFile])
+	}
+
+	// local dependencies are uploaded
+	if args.Upload != "testdata/py.py,testdata/py2.py,testdata/jar.jar" {
+		t.Errorf("unexpected uploads %s", args.Upload)
+	}
+}
+
 func TestInitConf(t *testing.T) {
 	sec, err := InitConf("testdata/configuration.ini")
 	if err != nil {
@@ -229,7 +364,389 @@ func TestPrintLog(t *testing.T) {
 		},
 	}
 
-	if PrintLog(log) != 2 {
+	var buf bytes.Buffer
+	defer func(o *Output) { out = o }(out)
+	out = NewOutput(OutputText, &buf)
+
+	PrintLog(JobID, log)
+	if buf.String() != "My first log\nMy seconf log\n" {
+		t.Errorf("unexpected logs %q", buf.String())
+	}
+}
+
+func TestParseFilePath(t *testing.T) {
+	protocol, container, object, err := ParseFilePath("s3://odp/test/spark-examples.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if protocol != "s3" || container != "odp" || object != "test/spark-examples.jar" {
+		t.Fail()
+	}
+
+	protocol, container, object, err = ParseFilePath("swift://odp/spark-examples.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if protocol != "swift" || container != "odp" || object != "spark-examples.py" {
+		t.Fail()
+	}
+}
+
+func TestParseFilePathErr(t *testing.T) {
+	for _, file := range []string{"spark-examples.jar", "s3://odp", "s3://odp/", "://odp/spark-examples.jar"} {
+		if _, _, _, err := ParseFilePath(file); err == nil {
+			t.Errorf("%s should be invalid", file)
+		}
+	}
+}
+
+func TestWriteDetachedJob(t *testing.T) {
+	job := &JobStatus{
+		ID:     JobID,
+		Name:   "hello",
+		Region: "GRA",
+		Status: JobStatusPENDING,
+	}
+
+	jobIDFile := filepath.Join(t.TempDir(), "job.json")
+	if err := WriteDetachedJob(ProjectID, job, jobIDFile); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(jobIDFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detachedJob := &DetachedJob{}
+	if err := json.Unmarshal(content, detachedJob); err != nil {
+		t.Fatal(err)
+	}
+
+	if detachedJob.ID != JobID || detachedJob.ProjectID != ProjectID || detachedJob.Status != JobStatusPENDING {
+		t.Fail()
+	}
+}
+
+func TestSignalPolicy(t *testing.T) {
+	if SignalPolicy("", true) != OnSignalAsk || SignalPolicy(OnSignalAsk, true) != OnSignalAsk {
+		t.Fail()
+	}
+
+	if SignalPolicy("", false) != OnSignalKill || SignalPolicy(OnSignalAsk, false) != OnSignalKill {
+		t.Fail()
+	}
+
+	if SignalPolicy(OnSignalDetach, false) != OnSignalDetach || SignalPolicy(OnSignalKill, true) != OnSignalKill {
+		t.Fail()
+	}
+}
+
+func TestKillAndWait(t *testing.T) {
+	// Init test
+	var InputRequest *http.Request
+	ts, ovh := initMockServer(&InputRequest, 200, `{"id":"`+JobID+`","status":"CANCELLING"}`, nil, time.Duration(0))
+	defer ts.Close()
+
+	client := &Client{
+		OVH:       ovh,
+		ProjectID: ProjectID,
+		JobID:     JobID,
+	}
+
+	job, err := KillAndWait(client, &JobStatus{ID: JobID, Status: JobStatusRUNNING}, nil)
+	if !errors.Is(err, ErrJobKilled) {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if job.Status != JobStatusCANCELLING {
+		t.Fail()
+	}
+}
+
+func TestKillAndWaitErr(t *testing.T) {
+	// Init test
+	var InputRequest *http.Request
+	ts, ovh := initMockServer(&InputRequest, 404, `{"message":"job not found"}`, nil, time.Duration(0))
+	defer ts.Close()
+
+	client := &Client{
+		OVH:       ovh,
+		ProjectID: ProjectID,
+		JobID:     JobID,
+	}
+
+	_, err := KillAndWait(client, &JobStatus{ID: JobID, Status: JobStatusRUNNING}, nil)
+	if err == nil || errors.Is(err, ErrJobKilled) {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestDryRunUploads(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.py", "utils.py"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("print('hello')"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	uploads, err := DryRunUploads(dir, nil, "s3://bucket/main.py", []string{SwiftConfig, S3Config}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(uploads) != 2 {
+		t.Fatalf("unexpected uploads %+v", uploads)
+	}
+	if uploads[0].Source != filepath.Join(dir, "main.py") || uploads[0].Protocol != S3Config ||
+		uploads[0].Container != "bucket" || uploads[0].Object != "main.py" {
+		t.Errorf("unexpected upload %+v", uploads[0])
+	}
+
+	if _, err := DryRunUploads(dir, nil, "swift://container/main.py", []string{S3Config}, nil); err == nil {
+		t.Error("unconfigured protocol must fail")
+	}
+
+	uploads, err = DryRunUploads("", nil, "swift://container/main.py", nil, nil)
+	if err != nil || len(uploads) != 0 {
 		t.Fail()
 	}
+
+	// the generated files are listed, without being generated
+	pkg := filepath.Join(dir, "mypkg")
+	if err := os.Mkdir(pkg, 0755); err != nil {
+		t.Fatal(err)
+	}
+	generated := []*GeneratedUpload{{Source: pkg, Name: "mypkg.zip"}}
+	uploads, err = DryRunUploads("", generated, "swift://container/main.py", []string{SwiftConfig}, &upload.Options{Prefix: "jobs"})
+	if err != nil || len(uploads) != 1 || uploads[0].Source != pkg || uploads[0].Object != "jobs/mypkg.zip" {
+		t.Fatalf("unexpected uploads %+v: %v", uploads, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mypkg.zip")); !os.IsNotExist(err) {
+		t.Error("the python package must not be zipped")
+	}
+}
+
+func TestPrintDryRun(t *testing.T) {
+	var buf strings.Builder
+	jobSubmit := &JobSubmit{Name: "hello", Engine: Engine, ContainerName: "container"}
+	uploads := []*DryRunUpload{{Source: "main.py", Protocol: SwiftConfig, Container: "container", Object: "main.py"}}
+
+	if err := PrintDryRun(&buf, ProjectID, jobSubmit, uploads); err != nil {
+		t.Fatal(err)
+	}
+
+	dryRun := &DryRun{}
+	if err := json.Unmarshal([]byte(buf.String()), dryRun); err != nil {
+		t.Fatalf("invalid dry run %q: %s", buf.String(), err)
+	}
+
+	if dryRun.ProjectID != ProjectID || dryRun.Path != "/cloud/project/"+ProjectID+"/dataProcessing/jobs" {
+		t.Errorf("unexpected dry run %+v", dryRun)
+	}
+	if dryRun.Payload == nil || dryRun.Payload.Name != "hello" || len(dryRun.Uploads) != 1 {
+		t.Errorf("unexpected dry run %+v", dryRun)
+	}
+}
+
+func TestUploadOptions(t *testing.T) {
+	a := &CLIArgs{File: "swift://odp/jobs/team-a/app.py", UploadExclude: "tests, *.log"}
+	options := a.UploadOptions()
+
+	// in the directory of the job file by default
+	if options.Prefix != "jobs/team-a" {
+		t.Errorf("unexpected prefix %s", options.Prefix)
+	}
+
+	if len(options.Exclude) != 2 || options.Exclude[1] != "*.log" || len(options.Include) != 0 {
+		t.Errorf("unexpected options %+v", options)
+	}
+
+	a = &CLIArgs{File: "swift://odp/app.py"}
+	if a.UploadOptions().Prefix != "" {
+		t.Fail()
+	}
+
+	a = &CLIArgs{File: "swift://odp/jobs/app.py", UploadPrefix: "/libs/"}
+	if a.UploadOptions().Prefix != "libs" {
+		t.Fail()
+	}
+
+	a = &CLIArgs{File: "swift://odp/jobs/app.py", UploadPrefix: "/"}
+	if a.UploadOptions().Prefix != "" {
+		t.Fail()
+	}
+}
+
+func TestParsArgsUploadVersion(t *testing.T) {
+	// These are the args you would pass in on the command line
+	os.Setenv("OS_PROJECT_ID", "1377b21260f05b410e4652445ac7c95b")
+	os.Args = strings.Split("./ovh-spark-submit --driver-cores 1 --driver-memory 4G --executor-cores 1 --executor-memory 1G --num-executors 1 --upload testdata/py.py --py-files testdata/py2.py,s3://odp/libs/helpers.zip --upload-version run-42 s3://odp/jobs/py.py 1000", " ")
+	utils.CleanArgs()
+	args = CLIArgs{}
+	defer func() {
+		args = CLIArgs{}
+	}()
+	parser := arg.MustParse(&args)
+
+	job := ParsArgs(*parser)
+
+	parameters := map[string]string{}
+	for _, params := range job.EngineParameters {
+		parameters[params.Name] = params.Value
+	}
+
+	if parameters[ParameterMainCode] != "jobs/_versions/run-42/py.py" {
+		t.Errorf("unexpected main code %s", parameters[ParameterMainCode])
+	}
+	if args.sparkProperties != "spark.submit.pyFiles jobs/_versions/run-42/py2.py,libs/helpers.zip\n" {
+		t.Errorf("unexpected spark properties %q", args.sparkProperties)
+	}
+	if parameters[ParameterPropertiesFile] != "jobs/_versions/run-42/"+PropertiesFileName(args.sparkProperties) {
+		t.Errorf("unexpected properties file %s", parameters[ParameterPropertiesFile])
+	}
+}
+
+func TestParsArgsUploadVersionHash(t *testing.T) {
+	// These are the args you would pass in on the command line
+	os.Setenv("OS_PROJECT_ID", "1377b21260f05b410e4652445ac7c95b")
+	os.Args = strings.Split("./ovh-spark-submit --driver-cores 1 --driver-memory 4G --executor-cores 1 --executor-memory 1G --num-executors 1 --upload testdata/py.py --upload-version hash s3://odp/py.py 1000", " ")
+	utils.CleanArgs()
+	args = CLIArgs{}
+	defer func() {
+		args = CLIArgs{}
+	}()
+	parser := arg.MustParse(&args)
+
+	job := ParsArgs(*parser)
+
+	if args.UploadVersion == UploadVersionHash || len(args.UploadVersion) != 16 {
+		t.Fatalf("unexpected version %s", args.UploadVersion)
+	}
+
+	for _, params := range job.EngineParameters {
+		if params.Name == ParameterMainCode && params.Value != "_versions/"+args.UploadVersion+"/py.py" {
+			t.Errorf("unexpected main code %s", params.Value)
+		}
+	}
+}
+
+func TestParsArgsUploadPrefixMainFile(t *testing.T) {
+	// These are the args you would pass in on the command line
+	os.Setenv("OS_PROJECT_ID", "1377b21260f05b410e4652445ac7c95b")
+	os.Args = strings.Split("./ovh-spark-submit --driver-cores 1 --driver-memory 4G --executor-cores 1 --executor-memory 1G --num-executors 1 --upload testdata/py.py --upload-prefix /libs/ s3://odp/jobs/py.py 1000", " ")
+	utils.CleanArgs()
+	args = CLIArgs{}
+	defer func() {
+		args = CLIArgs{}
+	}()
+	parser := arg.MustParse(&args)
+
+	job := ParsArgs(*parser)
+
+	// the main file is uploaded under the prefix, not in the directory of FILE
+	for _, params := range job.EngineParameters {
+		if params.Name == ParameterMainCode && params.Value != "libs/py.py" {
+			t.Errorf("unexpected main code %s", params.Value)
+		}
+	}
+
+	// a job file which isn't uploaded is kept
+	args = CLIArgs{File: "s3://odp/jobs/main.py", Upload: "testdata/py.py", UploadPrefix: "libs"}
+	if object, err := args.mainObject("jobs/main.py"); err != nil || object != "jobs/main.py" {
+		t.Errorf("unexpected main code %s: %v", object, err)
+	}
+
+	// at the root of the container, under the version of the upload
+	args = CLIArgs{File: "s3://odp/jobs/py.py", Upload: "testdata/py.py", UploadPrefix: "/", UploadVersion: "run-42"}
+	if object, err := args.mainObject("jobs/py.py"); err != nil || object != "_versions/run-42/py.py" {
+		t.Errorf("unexpected main code %s: %v", object, err)
+	}
+}
+
+func TestLoopLastLogs(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RequestURI == "/auth/time" {
+			fmt.Fprint(w, MockTime)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/logs") {
+			// the last logs come with the logs address
+			fmt.Fprint(w, `{"logs":[{"id":1,"timestamp":"2019-12-03T09:40:15Z","content":"last log"}],"logsAddress":"https://storage/v1/AUTH_xxx/odp-logs?prefix=job"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"id":"%s","status":"COMPLETED"}`, JobID)
+	}))
+	defer ts.Close()
+
+	defer func(o *Output) { out = o }(out)
+	var buf bytes.Buffer
+	out = NewOutput(OutputJSON, &buf)
+
+	ovhClient, _ := ovh.NewClient(ts.URL, MockApplicationKey, MockApplicationSecret, MockConsumerKey)
+	client := &Client{OVH: ovhClient, ProjectID: ProjectID}
+	if _, err := Loop(client, &JobStatus{ID: JobID, Status: JobStatusPENDING}, OnSignalKill); err != nil {
+		t.Fatal(err)
+	}
+
+	events := readEvents(t, &buf)
+	if len(events) != 3 || events[1].Type != EventLog || events[1].Log.Content != "last log" || events[2].Type != EventLogsAddress {
+		t.Errorf("unexpected events %q", buf.String())
+	}
+	if client.LogsAddress == "" {
+		t.Error("the logs address must be kept")
+	}
+}
+
+func TestPrintLogPages(t *testing.T) {
+	pages := []string{
+		`{"logs":[{"id":1,"timestamp":"2019-12-03T09:40:13Z","content":"first"},{"id":2,"timestamp":"2019-12-03T09:40:14Z","content":"second"}]}`,
+		`{"logs":[{"id":2,"timestamp":"2019-12-03T09:40:14Z","content":"second"},{"id":3,"timestamp":"2019-12-03T09:40:15Z","content":"third"}],"logsAddress":"https://storage/v1/AUTH_xxx/odp-logs?prefix=job"}`,
+	}
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.RequestURI == "/auth/time" {
+			fmt.Fprint(w, MockTime)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, pages[calls])
+		calls++
+	}))
+	defer ts.Close()
+
+	defer func(o *Output) { out = o }(out)
+	var buf bytes.Buffer
+	out = NewOutput(OutputJSON, &buf)
+
+	ovhClient, _ := ovh.NewClient(ts.URL, MockApplicationKey, MockApplicationSecret, MockConsumerKey)
+	client := &Client{OVH: ovhClient, ProjectID: ProjectID}
+	if err := PrintLogPages(client, JobID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// every page is printed, each line once
+	events := readEvents(t, &buf)
+	if calls != 2 || len(events) != 4 || events[2].Log.Content != "third" || events[3].Type != EventLogsAddress {
+		t.Errorf("unexpected events %q", buf.String())
+	}
+}
+
+func TestCleanupObjects(t *testing.T) {
+	a := &CLIArgs{File: "swift://odp/jobs/main.py", sparkProperties: "spark.eventLog.enabled false\n"}
+	properties := "jobs/" + PropertiesFileName(a.sparkProperties)
+
+	// the generated properties file is shared with the other runs
+	objects := a.cleanupObjects([]string{"jobs/main.py", properties})
+	if len(objects) != 1 || objects[0] != "jobs/main.py" {
+		t.Errorf("unexpected objects %v", objects)
+	}
+
+	a.sparkProperties = ""
+	if objects := a.cleanupObjects([]string{"jobs/main.py", properties}); len(objects) != 2 {
+		t.Errorf("unexpected objects %v", objects)
+	}
 }
@@ -0,0 +1,86 @@
+package upload
+
+import (
+	"io"
+	"log"
+	"sync"
+
+	humanize "github.com/dustin/go-humanize"
+)
+
+// progressStep percentage of the total size between two progress logs
+const progressStep = 10
+
+type (
+	// progress of an upload, logged every progressStep percent and after each file
+	progress struct {
+		mu        sync.Mutex
+		files     int
+		doneFiles int
+		skipped   int
+		total     int64
+		done      int64
+		step      int64
+	}
+
+	// progressReader reader adding the bytes read to the progress
+	progressReader struct {
+		r io.Reader
+		p *progress
+	}
+)
+
+func newProgress(files []*File) *progress {
+	p := &progress{files: len(files)}
+	for _, file := range files {
+		p.total += file.Size
+	}
+	return p
+}
+
+// add bytes to the upload progress
+func (p *progress) add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done += n
+	if p.total == 0 {
+		return
+	}
+	if step := p.done * 100 / p.total / progressStep; step > p.step && p.done < p.total {
+		p.step = step
+		log.Printf("Uploading: %d%% (%s/%s)", step*progressStep, humanize.IBytes(uint64(p.done)), humanize.IBytes(uint64(p.total)))
+	}
+}
+
+// fileDone log the upload of a file, or that it has been skipped
+func (p *progress) fileDone(file *File, uploaded bool) {
+	if !uploaded {
+		p.add(file.Size)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.doneFiles++
+	if !uploaded {
+		p.skipped++
+		log.Printf("File %s unchanged, skipped (%d/%d)", file.Path, p.doneFiles, p.files)
+		return
+	}
+	log.Printf("File %s uploaded (%d/%d)", file.Path, p.doneFiles, p.files)
+}
+
+// summary log the number of uploaded and skipped files
+func (p *progress) summary() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	log.Printf("%d file(s) uploaded, %d unchanged file(s) skipped", p.doneFiles-p.skipped, p.skipped)
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.add(int64(n))
+	return n, err
+}
@@ -0,0 +1,168 @@
+package upload
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+const (
+	// DefaultPartSize size in MiB of the parts of the multipart uploads
+	DefaultPartSize = 16
+	// deleteBatchSize maximal number of objects deleted by request
+	deleteBatchSize = 1000
+)
+
+type (
+	S3 struct {
+		StorageI
+		c        *s3.S3
+		uploader *s3manager.Uploader
+	}
+
+	S3Conf struct {
+		Endpoint  string `ini:"endpoint"`
+		Region    string `ini:"region"`
+		AccessKey string `ini:"access_key"`
+		SecretKey string `ini:"secret_key"`
+		// PathStyle use path-style addressing (https://endpoint/bucket/object)
+		// instead of virtual-hosted-style addressing (https://bucket.endpoint/object)
+		PathStyle bool `ini:"path_style"`
+		// PartSize size in MiB of the parts of the multipart uploads, used for the files bigger than a part
+		PartSize int64 `ini:"part_size"`
+	}
+)
+
+// init storage
+func NewS3(conf *S3Conf) (*S3, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(conf.Endpoint),
+		Region:           aws.String(conf.Region),
+		Credentials:      credentials.NewStaticCredentials(conf.AccessKey, conf.SecretKey, ""),
+		S3ForcePathStyle: aws.Bool(conf.PathStyle),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	partSize := conf.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+
+	c := s3.New(sess)
+	return &S3{
+		c: c,
+		uploader: s3manager.NewUploaderWithClient(c, func(u *s3manager.Uploader) {
+			u.PartSize = partSize << 20
+		}),
+	}, nil
+}
+
+func (s *S3) Upload(sources []string, dest string, options *Options) ([]string, error) {
+	return upload(s, sources, dest, options)
+}
+
+// put stream the object, with a multipart upload when it's bigger than a part
+func (s *S3) put(r io.Reader, size int64, dest, object, contentType, md5 string) error {
+	_, err := s.uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(dest),
+		Key:         aws.String(object),
+		Body:        r,
+		ContentType: aws.String(contentType),
+		Metadata:    map[string]*string{ChecksumMetadata: aws.String(md5)},
+	})
+	return err
+}
+
+// checksum md5 of the object from its metadata, or its ETag for a simple object
+func (s *S3) checksum(dest, object string) (string, bool, error) {
+	res, err := s.c.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(dest),
+		Key:    aws.String(object),
+	})
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+
+	for key, value := range res.Metadata {
+		if strings.EqualFold(key, ChecksumMetadata) {
+			return aws.StringValue(value), true, nil
+		}
+	}
+	// the ETag of a multipart object isn't the md5 of its content
+	etag := strings.Trim(aws.StringValue(res.ETag), "\"")
+	if strings.Contains(etag, "-") {
+		return "", true, nil
+	}
+	return etag, true, nil
+}
+
+// List the objects of the bucket with the prefix
+func (s *S3) List(dest, prefix string) ([]*Object, error) {
+	var list []*Object
+	err := s.c.ListObjectsV2Pages(&s3.ListObjectsV2Input{
+		Bucket: aws.String(dest),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			list = append(list, &Object{Name: aws.StringValue(object.Key), LastModified: aws.TimeValue(object.LastModified)})
+		}
+		return true
+	})
+	return list, err
+}
+
+// Delete the objects of the bucket, by batches of deleteBatchSize objects
+func (s *S3) Delete(dest string, objects []string) error {
+	for start := 0; start < len(objects); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(objects) {
+			end = len(objects)
+		}
+
+		identifiers := make([]*s3.ObjectIdentifier, 0, end-start)
+		for _, object := range objects[start:end] {
+			identifiers = append(identifiers, &s3.ObjectIdentifier{Key: aws.String(object)})
+		}
+		res, err := s.c.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(dest),
+			Delete: &s3.Delete{Objects: identifiers, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return err
+		}
+		if len(res.Errors) > 0 {
+			return fmt.Errorf("unable to delete %s: %s", aws.StringValue(res.Errors[0].Key), aws.StringValue(res.Errors[0].Message))
+		}
+	}
+	return nil
+}
+
+// Download the content of the object of the bucket
+func (s *S3) Download(dest, object string, w io.Writer) error {
+	res, err := s.c.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(dest),
+		Key:    aws.String(object),
+	})
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	_, err = io.Copy(w, res.Body)
+	return err
+}
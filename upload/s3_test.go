@@ -0,0 +1,280 @@
+package upload
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+// newS3TestServer start an in-process S3 server with the test bucket created
+func newS3TestServer(t *testing.T) (*httptest.Server, *S3) {
+	backend := s3mem.New()
+	srv := httptest.NewServer(gofakes3.New(backend).Server())
+
+	if err := backend.CreateBucket(testContainer); err != nil {
+		srv.Close()
+		t.Fatal("Failed to create bucket", err)
+	}
+
+	s, err := NewS3(&S3Conf{
+		Endpoint:  srv.URL,
+		Region:    "gra",
+		AccessKey: "AccessKey",
+		SecretKey: "SecretKey",
+		PathStyle: true,
+	})
+	if err != nil {
+		srv.Close()
+		t.Fatal("Failed to create client", err)
+	}
+	return srv, s
+}
+
+// listS3Objects list the object names of the test bucket
+func listS3Objects(t *testing.T, s *S3) []string {
+	res, err := s.c.ListObjects(&s3.ListObjectsInput{Bucket: aws.String(testContainer)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(res.Contents))
+	for _, object := range res.Contents {
+		names = append(names, aws.StringValue(object.Key))
+	}
+	return names
+}
+
+func TestNewS32(t *testing.T) {
+	conf := &S3Conf{
+		Endpoint:  "https://s3.gra.io.cloud.ovh.net",
+		Region:    "gra",
+		AccessKey: "AccessKey",
+		SecretKey: "SecretKey",
+		PathStyle: true,
+	}
+
+	s, err := NewS3(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if aws.StringValue(s.c.Config.Endpoint) != conf.Endpoint {
+		t.Fail()
+	}
+	if aws.StringValue(s.c.Config.Region) != conf.Region {
+		t.Fail()
+	}
+	if !aws.BoolValue(s.c.Config.S3ForcePathStyle) {
+		t.Fail()
+	}
+	creds, err := s.c.Config.Credentials.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.AccessKeyID != conf.AccessKey || creds.SecretAccessKey != conf.SecretKey {
+		t.Fail()
+	}
+}
+
+func TestS3PutFile(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	_, err := s.Upload([]string{"../testdata/jar.jar"}, testContainer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !stringInSlice("jar.jar", listS3Objects(t, s)) {
+		t.Fail()
+	}
+}
+
+func TestS3PutFiles(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	_, err := s.Upload([]string{"../testdata/"}, testContainer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := listS3Objects(t, s)
+
+	if !stringInSlice("jar.jar", res) {
+		t.Fail()
+	}
+
+	if !stringInSlice("py.py", res) {
+		t.Fail()
+	}
+
+	if !stringInSlice("py2.py", res) {
+		t.Fail()
+	}
+
+	if !stringInSlice("configuration.ini", res) {
+		t.Fail()
+	}
+}
+
+func TestS3PutTree(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "main.py", "lib/v1.2/helpers.py", "lib/v1.2/test_helpers.py")
+
+	_, err := s.Upload([]string{dir}, testContainer, &Options{Exclude: []string{"test_*"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res := listS3Objects(t, s)
+	if len(res) != 2 || !stringInSlice("main.py", res) || !stringInSlice("lib/v1.2/helpers.py", res) {
+		t.Errorf("unexpected objects %q", res)
+	}
+}
+
+func TestS3PutMultipart(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	// 6MiB file uploaded in two parts of 5MiB, the minimal part size
+	s.uploader.PartSize = 5 << 20
+	dir := t.TempDir()
+	content := bytes.Repeat([]byte("0123456789abcdef"), 6<<16)
+	if err := os.WriteFile(filepath.Join(dir, "big.jar"), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Upload([]string{filepath.Join(dir, "big.jar")}, testContainer, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := s.c.GetObject(&s3.GetObjectInput{Bucket: aws.String(testContainer), Key: aws.String("big.jar")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	uploaded, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(uploaded, content) {
+		t.Errorf("unexpected content of %d bytes", len(uploaded))
+	}
+}
+
+func TestS3PutParallel(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.py", "b.py", "c.py", "lib/d.py", "lib/e.py")
+
+	if _, err := s.Upload([]string{dir}, testContainer, &Options{Parallelism: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := listS3Objects(t, s); len(res) != 5 {
+		t.Errorf("unexpected objects %q", res)
+	}
+}
+
+func TestS3UploadCreated(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "main.py")
+	if _, err := s.Upload([]string{filepath.Join(dir, "main.py")}, testContainer, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the overwritten objects existed before the upload, only the new ones are returned
+	writeFiles(t, dir, "lib.py")
+	created, err := s.Upload([]string{dir}, testContainer, &Options{Force: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 1 || created[0] != "lib.py" {
+		t.Errorf("unexpected created objects %q", created)
+	}
+}
+
+func TestS3PutUnchanged(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	// small file and multipart file
+	dir := t.TempDir()
+	writeFiles(t, dir, "main.py")
+	if err := os.WriteFile(filepath.Join(dir, "big.jar"), bytes.Repeat([]byte("0123456789abcdef"), 6<<16), 0600); err != nil {
+		t.Fatal(err)
+	}
+	s.uploader.PartSize = 5 << 20
+
+	for _, name := range []string{"main.py", "big.jar"} {
+		source := filepath.Join(dir, name)
+		if uploaded, _, err := putFile(s, source, testContainer, name, false, nil); err != nil || !uploaded {
+			t.Fatalf("%s must be uploaded: %v", name, err)
+		}
+
+		if uploaded, _, err := putFile(s, source, testContainer, name, false, nil); err != nil || uploaded {
+			t.Errorf("unchanged %s must be skipped: %v", name, err)
+		}
+
+		if uploaded, _, err := putFile(s, source, testContainer, name, true, nil); err != nil || !uploaded {
+			t.Errorf("%s must be uploaded when forced: %v", name, err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.py"), []byte("print('changed')"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if uploaded, _, err := putFile(s, filepath.Join(dir, "main.py"), testContainer, "main.py", false, nil); err != nil || !uploaded {
+		t.Errorf("changed file must be uploaded: %v", err)
+	}
+}
+
+func TestS3Download(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	if _, err := s.Upload([]string{"../testdata/py.py"}, testContainer, nil); err != nil {
+		t.Fatal(err)
+	}
+	expected, err := os.ReadFile("../testdata/py.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := s.Download(testContainer, "py.py", &b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b.Bytes(), expected) {
+		t.Errorf("unexpected content %q", b.String())
+	}
+
+	if err := s.Download(testContainer, "missing.py", &b); err == nil {
+		t.Error("downloading a missing object must fail")
+	}
+}
+
+func TestS3PutFileMissingBucket(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	if _, err := s.Upload([]string{"../testdata/jar.jar"}, "missing", nil); err == nil {
+		t.Fail()
+	}
+}
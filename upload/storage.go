@@ -1,14 +1,78 @@
 package upload
 
 import (
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	ini "gopkg.in/ini.v1"
+
+	"data-processing-spark-submit/utils"
+)
+
+const (
+	// DefaultParallelism number of files uploaded concurrently by default
+	DefaultParallelism = 4
+	// ChecksumMetadata name of the metadata storing the md5 of the content of the uploaded objects,
+	// the ETag isn't the md5 of the content of large/multipart objects
+	ChecksumMetadata = "md5"
 )
 
 type (
 	StorageI interface {
-		Upload(source, dest string) error
+		// Upload the files of the sources in the dest container, it returns the names of the objects it created,
+		// the ones which already existed excluded, even when it failed
+		Upload(sources []string, dest string, options *Options) ([]string, error)
+		// List the objects of the dest container whose name starts with the prefix
+		List(dest, prefix string) ([]*Object, error)
+		// Delete the objects of the dest container
+		Delete(dest string, objects []string) error
+		// Download the content of the object of the dest container
+		Download(dest, object string, w io.Writer) error
+	}
+
+	// Object object of a container
+	Object struct {
+		Name         string
+		LastModified time.Time
+	}
+
+	// Options of an upload, the zero value uploads every file
+	Options struct {
+		// Include glob patterns of the files of a directory to upload, all the files when empty
+		Include []string
+		// Exclude glob patterns of the files and sub directories of a directory not to upload
+		Exclude []string
+		// Prefix of the object names in the container, the container root when empty
+		Prefix string
+		// Parallelism number of files uploaded concurrently, DefaultParallelism when not set
+		Parallelism int
+		// Force upload the files even when their content is already in the container
+		Force bool
+	}
+
+	// File local file to upload
+	File struct {
+		Path string
+		// Object name of the file in the container
+		Object string
+		Size   int64
+	}
+
+	// putter storage creating/overriding an object from a stream of the given size
+	putter interface {
+		// put the object, storing the md5 of its content in its ChecksumMetadata
+		put(r io.Reader, size int64, dest, object, contentType, md5 string) error
+		// checksum get the md5 of the content of the object, empty when it's unknown, and whether the object exists
+		checksum(dest, object string) (string, bool, error)
 	}
 )
 
@@ -21,8 +85,221 @@ func New(section *ini.Section, protocol string) (StorageI, error) {
 			return nil, err
 		}
 		return NewSwift(s)
+	case "s3":
+		s := new(S3Conf)
+		if err := section.MapTo(s); err != nil {
+			return nil, err
+		}
+		return NewS3(s)
 	default:
 		return nil, fmt.Errorf("%s protocol not implemented yet", protocol)
 	}
 
 }
+
+// Files list the files to upload for the given source: the source file itself, named after its base name,
+// or the files of the source directory and its sub directories, named after their path relative to it.
+// Object names start with the prefix, the include/exclude patterns only apply to the content of a directory
+func Files(source string, options *Options) ([]*File, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []*File{{Path: source, Object: path.Join(options.Prefix, filepath.Base(source)), Size: info.Size()}}, nil
+	}
+
+	var files []*File
+	err = filepath.WalkDir(source, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, p)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if Match(options.Exclude, rel) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || (len(options.Include) > 0 && !Match(options.Include, rel)) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, &File{Path: p, Object: path.Join(options.Prefix, rel), Size: info.Size()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Match test if the relative path matches one of the glob patterns. A pattern containing a "/"
+// is matched against the whole path, otherwise against its base name
+func Match(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if matched, err := path.Match(pattern, name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidPatterns check the syntax of the glob patterns
+func ValidPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %s: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// upload put the files of the sources in the dest container, with a bounded pool of workers.
+// It stops at the first failed upload and returns the names of the objects created, the skipped and overwritten ones excluded
+func upload(s putter, sources []string, dest string, options *Options) ([]string, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	var files []*File
+	for _, source := range sources {
+		sourceFiles, err := Files(source, options)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sourceFiles...)
+	}
+
+	parallelism := options.Parallelism
+	if parallelism <= 0 {
+		parallelism = DefaultParallelism
+	}
+	if parallelism > len(files) {
+		parallelism = len(files)
+	}
+
+	p := newProgress(files)
+	queue := make(chan *File)
+	errs := make(chan error, len(files))
+	var mu sync.Mutex
+	var created []string
+	var wg sync.WaitGroup
+	for i := 0; i < parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range queue {
+				put, existed, err := putFile(s, file.Path, dest, file.Object, options.Force, p)
+				if put && !existed {
+					// a failed upload may have created the object too
+					mu.Lock()
+					created = append(created, file.Object)
+					mu.Unlock()
+				}
+				if err != nil {
+					errs <- fmt.Errorf("unable to upload %s: %w", file.Path, err)
+					continue
+				}
+				p.fileDone(file, put)
+			}
+		}()
+	}
+
+	var err error
+	for _, file := range files {
+		select {
+		case err = <-errs:
+		default:
+		}
+		if err != nil {
+			break
+		}
+		queue <- file
+	}
+	close(queue)
+	wg.Wait()
+	close(errs)
+
+	if err == nil {
+		err = <-errs
+	}
+	if err != nil {
+		return created, err
+	}
+	p.summary()
+	return created, nil
+}
+
+// putFile stream the local file to the object of the dest container. Unless forced, the upload is skipped
+// when the object has the same content. It returns whether the file has been uploaded (or its upload attempted
+// upon error) and whether the object existed before
+func putFile(s putter, source, dest, object string, force bool, p *progress) (put bool, existed bool, err error) {
+	file, err := os.Open(source)
+	if err != nil {
+		return false, false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, false, err
+	}
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return false, false, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return false, false, err
+	}
+
+	// the existence is checked even when forced, the objects which existed mustn't be cleaned up
+	remote, existed, err := s.checksum(dest, object)
+	if err != nil {
+		return false, false, err
+	}
+	if !force && existed && remote == sum {
+		return false, true, nil
+	}
+
+	var r io.Reader = file
+	if p != nil {
+		r = &progressReader{r: file, p: p}
+	}
+	return true, existed, s.put(r, info.Size(), dest, object, utils.DetectMimeType(source), sum)
+}
+
+// copyFile copy the content of the source file to the writer
+func copyFile(w io.Writer, source string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
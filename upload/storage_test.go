@@ -1,6 +1,8 @@
 package upload
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"gopkg.in/ini.v1"
@@ -25,3 +27,89 @@ func TestNewSwift(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestNewS3(t *testing.T) {
+
+	f := ini.Empty()
+	sec, _ := f.NewRawSection("s3", `endpoint=https://s3.gra.io.cloud.ovh.net
+region=gra`)
+	_, err := New(sec, "s3")
+	if err != nil {
+		t.Fail()
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "main.py", "lib/v1.2/helpers.py", "lib/v1.2/helpers.log", "tests/test_main.py")
+
+	files, err := Files(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := make([]string, 0, len(files))
+	for _, f := range files {
+		objects = append(objects, f.Object)
+	}
+	if strings.Join(objects, " ") != "lib/v1.2/helpers.log lib/v1.2/helpers.py main.py tests/test_main.py" {
+		t.Errorf("unexpected objects %q", objects)
+	}
+
+	files, err = Files(dir, &Options{Include: []string{"*.py"}, Exclude: []string{"tests"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Object != "lib/v1.2/helpers.py" || files[1].Object != "main.py" {
+		t.Errorf("unexpected files %+v", files)
+	}
+
+	// a file is uploaded under its base name, whatever the patterns
+	files, err = Files(filepath.Join(dir, "lib/v1.2/helpers.log"), &Options{Include: []string{"*.py"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Object != "helpers.log" {
+		t.Errorf("unexpected files %+v", files)
+	}
+
+	files, err = Files(dir, &Options{Include: []string{"main.py"}, Prefix: "jobs/team-a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Object != "jobs/team-a/main.py" {
+		t.Errorf("unexpected files %+v", files)
+	}
+
+	if _, err := Files(filepath.Join(dir, "missing"), nil); err == nil {
+		t.Fail()
+	}
+}
+
+func TestMatch(t *testing.T) {
+	if !Match([]string{"*.py"}, "lib/helpers.py") {
+		t.Fail()
+	}
+
+	if !Match([]string{"lib/*.py"}, "lib/helpers.py") {
+		t.Fail()
+	}
+
+	if Match([]string{"lib/*.py"}, "lib/v1/helpers.py") {
+		t.Fail()
+	}
+
+	if Match(nil, "main.py") {
+		t.Fail()
+	}
+}
+
+func TestValidPatterns(t *testing.T) {
+	if err := ValidPatterns([]string{"*.py", "lib/[a-z]*"}); err != nil {
+		t.Fail()
+	}
+
+	if err := ValidPatterns([]string{"lib/[a-z"}); err == nil {
+		t.Fail()
+	}
+}
@@ -1,20 +1,29 @@
 package upload
 
 import (
-	"io/ioutil"
-	"log"
-	"path"
-	"path/filepath"
+	"errors"
+	"fmt"
+	"io"
 
 	"github.com/ncw/swift"
+)
 
-	"data-processing-spark-submit/utils"
+const (
+	// DefaultLargeObjectThreshold size in MiB from which files are uploaded as large objects
+	DefaultLargeObjectThreshold = 256
+	// DefaultSegmentSize size in MiB of the segments of the large objects
+	DefaultSegmentSize = 128
+	// SegmentContainerSuffix suffix of the container storing the segments of the large objects of a container
+	SegmentContainerSuffix = "_segments"
 )
 
 type (
 	Swift struct {
 		StorageI
 		c *swift.Connection
+		// largeObjectThreshold size in bytes from which files are uploaded as large objects
+		largeObjectThreshold int64
+		segmentSize          int64
 	}
 
 	SwiftConf struct {
@@ -23,6 +32,10 @@ type (
 		AuthURL  string `ini:"auth_url"`
 		Domain   string `ini:"domain"`
 		Region   string `ini:"region"`
+		// LargeObjectThreshold size in MiB from which files are uploaded as segmented large objects
+		LargeObjectThreshold int64 `ini:"large_object_threshold"`
+		// SegmentSize size in MiB of the segments of the large objects
+		SegmentSize int64 `ini:"segment_size"`
 	}
 )
 
@@ -35,51 +48,112 @@ func NewSwift(conf *SwiftConf) (*Swift, error) {
 		Domain:   conf.Domain,
 		Region:   conf.Region,
 	}
-	return &Swift{
-		c: c,
-	}, nil
-}
 
-func (s *Swift) Upload(source, dest string) error {
+	s := &Swift{
+		c:                    c,
+		largeObjectThreshold: conf.LargeObjectThreshold << 20,
+		segmentSize:          conf.SegmentSize << 20,
+	}
+	if s.largeObjectThreshold <= 0 {
+		s.largeObjectThreshold = DefaultLargeObjectThreshold << 20
+	}
+	if s.segmentSize <= 0 {
+		s.segmentSize = DefaultSegmentSize << 20
+	}
+	return s, nil
+}
 
-	if filepath.Ext(source) != "" {
-		// is dir
-		if err := s.Put(source, dest); err != nil {
-			return err
+func (s *Swift) Upload(sources []string, dest string, options *Options) ([]string, error) {
+	// authenticate once, the connection is then shared by the workers
+	if !s.c.Authenticated() {
+		if err := s.c.Authenticate(); err != nil {
+			return nil, err
 		}
+	}
+	return upload(s, sources, dest, options)
+}
 
-	} else {
-		files, err := ioutil.ReadDir(source)
-		if err != nil {
-			return err
-		}
+// put stream the object, as a static large object (or a dynamic one if not supported) from the threshold
+func (s *Swift) put(r io.Reader, size int64, dest, object, contentType, md5 string) error {
+	headers := swift.Metadata{ChecksumMetadata: md5}.ObjectHeaders()
+	if size < s.largeObjectThreshold {
+		_, err := s.c.ObjectPut(dest, object, r, true, md5, contentType, headers)
+		return err
+	}
 
-		for _, f := range files {
-			if err = s.Put(path.Join(source, f.Name()), dest); err != nil {
-				return err
-			}
-			log.Printf("File %s uploaded", filepath.Base(source))
+	opts := &swift.LargeObjectOpts{
+		Container:        dest,
+		ObjectName:       object,
+		ContentType:      contentType,
+		ChunkSize:        s.segmentSize,
+		SegmentContainer: dest + SegmentContainerSuffix,
+		Headers:          headers,
+	}
+	if err := s.c.ContainerCreate(opts.SegmentContainer, nil); err != nil {
+		return err
+	}
+	lo, err := s.c.StaticLargeObjectCreate(opts)
+	if errors.Is(err, swift.SLONotSupported) {
+		lo, err = s.c.DynamicLargeObjectCreate(opts)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(lo, r); err != nil {
+		if closeErr := lo.Close(); closeErr != nil {
+			return fmt.Errorf("%w, closing the large object failed too: %s", err, closeErr)
 		}
+		return err
 	}
-	return nil
+	return lo.Close()
 }
 
-// Upload create/override given file(s)
-func (s *Swift) Put(source, dest string) error {
-	err := s.c.Authenticate()
+// checksum md5 of the object from its metadata, or its ETag for a simple object
+func (s *Swift) checksum(dest, object string) (string, bool, error) {
+	info, headers, err := s.c.Object(dest, object)
+	if errors.Is(err, swift.ObjectNotFound) {
+		return "", false, nil
+	}
 	if err != nil {
-		return err
+		return "", false, err
 	}
 
-	byteFile, err := ioutil.ReadFile(source)
-	if err != nil {
-		return err
+	if md5 := headers.ObjectMetadata()[ChecksumMetadata]; md5 != "" {
+		return md5, true, nil
+	}
+	if headers.IsLargeObject() {
+		return "", true, nil
 	}
+	return info.Hash, true, nil
+}
 
-	err = s.c.ObjectPutBytes(dest, filepath.Base(source), byteFile, utils.DetectMimeType(source))
+// List the objects of the container with the prefix
+func (s *Swift) List(dest, prefix string) ([]*Object, error) {
+	objects, err := s.c.ObjectsAll(dest, &swift.ObjectsOpts{Prefix: prefix})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	list := make([]*Object, 0, len(objects))
+	for _, object := range objects {
+		list = append(list, &Object{Name: object.Name, LastModified: object.LastModified})
+	}
+	return list, nil
+}
+
+// Delete the objects of the container, with their segments for the large objects
+func (s *Swift) Delete(dest string, objects []string) error {
+	for _, object := range objects {
+		if err := s.c.LargeObjectDelete(dest, object); err != nil && !errors.Is(err, swift.ObjectNotFound) {
+			return err
+		}
 	}
-	s.c.UnAuthenticate()
 	return nil
 }
+
+// Download the content of the object of the container
+func (s *Swift) Download(dest, object string, w io.Writer) error {
+	_, err := s.c.ObjectGet(dest, object, w, false, nil)
+	return err
+}
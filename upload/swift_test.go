@@ -1,6 +1,9 @@
 package upload
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ncw/swift"
@@ -85,7 +88,7 @@ func TestSwiftPutFile(t *testing.T) {
 
 	s, _ := NewSwift(conf)
 
-	err = s.Upload("../testdata/jar.jar", testContainer)
+	_, err = s.Upload([]string{"../testdata/jar.jar"}, testContainer, nil)
 	if err != nil {
 		t.Fail()
 	}
@@ -134,7 +137,7 @@ func TestSwiftPutFiles(t *testing.T) {
 
 	s, _ := NewSwift(conf)
 
-	err = s.Upload("../testdata/", testContainer)
+	_, err = s.Upload([]string{"../testdata/"}, testContainer, nil)
 	if err != nil {
 		t.Fail()
 	}
@@ -161,3 +164,136 @@ func TestSwiftPutFiles(t *testing.T) {
 	}
 
 }
+
+func TestSwiftPutLargeObject(t *testing.T) {
+	srv, err := swifttest.NewSwiftServer("localhost")
+	if err != nil {
+		t.Fatal("Failed to create server", err)
+	}
+	defer srv.Close()
+
+	client := &swift.Connection{
+		UserName: swifttest.TEST_ACCOUNT,
+		ApiKey:   swifttest.TEST_ACCOUNT,
+		AuthUrl:  srv.AuthURL,
+	}
+	if err = client.Authenticate(); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.ContainerCreate(testContainer, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ := NewSwift(&SwiftConf{
+		UserName: swifttest.TEST_ACCOUNT,
+		Password: swifttest.TEST_ACCOUNT,
+		AuthURL:  srv.AuthURL,
+	})
+	// upload every file as a large object of 1KiB segments
+	s.largeObjectThreshold = 1
+	s.segmentSize = 1 << 10
+
+	dir := t.TempDir()
+	content := strings.Repeat("0123456789", 1000)
+	if err := os.WriteFile(filepath.Join(dir, "big.jar"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	uploadedObjects, err := s.Upload([]string{filepath.Join(dir, "big.jar")}, testContainer, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uploadedObjects) != 1 || uploadedObjects[0] != "big.jar" {
+		t.Errorf("unexpected uploaded objects %q", uploadedObjects)
+	}
+
+	uploaded, err := client.ObjectGetString(testContainer, "big.jar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uploaded != content {
+		t.Errorf("unexpected content of %d bytes", len(uploaded))
+	}
+
+	// the content of a large object is compared with its md5 metadata
+	if uploaded, _, err := putFile(s, filepath.Join(dir, "big.jar"), testContainer, "big.jar", false, nil); err != nil || uploaded {
+		t.Errorf("unchanged large object must be skipped: %v", err)
+	}
+
+	s.largeObjectThreshold = DefaultLargeObjectThreshold << 20
+	if err := os.WriteFile(filepath.Join(dir, "main.py"), []byte("print('hello')"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []bool{true, false} {
+		if uploaded, _, err := putFile(s, filepath.Join(dir, "main.py"), testContainer, "main.py", false, nil); err != nil || uploaded != expected {
+			t.Errorf("main.py uploaded %t instead of %t: %v", uploaded, expected, err)
+		}
+	}
+
+	// the segments of a large object are deleted with it
+	if err := s.Delete(testContainer, []string{"big.jar"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, container := range []string{testContainer, testContainer + SegmentContainerSuffix} {
+		names, err := client.ObjectNames(container, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stringInSlice("big.jar", names) || (container != testContainer && len(names) != 0) {
+			t.Errorf("unexpected objects %q in %s", names, container)
+		}
+	}
+}
+
+func TestSwiftListDelete(t *testing.T) {
+	srv, err := swifttest.NewSwiftServer("localhost")
+	if err != nil {
+		t.Fatal("Failed to create server", err)
+	}
+	defer srv.Close()
+
+	s, _ := NewSwift(&SwiftConf{
+		UserName: swifttest.TEST_ACCOUNT,
+		Password: swifttest.TEST_ACCOUNT,
+		AuthURL:  srv.AuthURL,
+	})
+	if err = s.c.Authenticate(); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.c.ContainerCreate(testContainer, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.Upload([]string{"../testdata/py.py", "../testdata/py2.py"}, testContainer, &Options{Prefix: "jobs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := s.List(testContainer, "jobs/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0].Name != "jobs/py.py" || objects[0].LastModified.IsZero() {
+		t.Fatalf("unexpected objects %+v", objects)
+	}
+
+	if err = s.Delete(testContainer, []string{"jobs/py.py", "jobs/missing.py"}); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err = s.List(testContainer, "jobs/")
+	if err != nil || len(objects) != 1 || objects[0].Name != "jobs/py2.py" {
+		t.Errorf("unexpected objects %+v: %v", objects, err)
+	}
+
+	expected, err := os.ReadFile("../testdata/py2.py")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err = s.Download(testContainer, "jobs/py2.py", &b); err != nil || b.String() != string(expected) {
+		t.Errorf("unexpected content %q: %v", b.String(), err)
+	}
+	if err = s.Download(testContainer, "jobs/py.py", &b); err == nil {
+		t.Error("downloading a deleted object must fail")
+	}
+}
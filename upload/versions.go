@@ -0,0 +1,140 @@
+package upload
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// VersionsDir directory of the versions of the uploaded files, under the upload prefix
+	VersionsDir = "_versions"
+	// hashLength number of hexadecimal characters of the content hashes
+	hashLength = 16
+	// VersionMarker object of a version rewritten by every run using it, dating its last use
+	VersionMarker = "_last_used"
+)
+
+// Version version of the uploaded files
+type Version struct {
+	Name    string
+	Objects []string
+	// LastModified date of its last use: the modification date of its VersionMarker,
+	// or the most recent one of its objects when it has no marker
+	LastModified time.Time
+	// marked the version has a VersionMarker
+	marked bool
+}
+
+// Hash hash of the content and object names of the files of the sources: the same files give the same hash
+func Hash(sources []string, options *Options) (string, error) {
+	var files []*File
+	for _, source := range sources {
+		sourceFiles, err := Files(source, options)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, sourceFiles...)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Object < files[j].Object
+	})
+
+	hash := sha256.New()
+	for _, file := range files {
+		hash.Write([]byte(file.Object + "\x00"))
+		if err := copyFile(hash, file.Path); err != nil {
+			return "", err
+		}
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:hashLength], nil
+}
+
+// VersionPrefix prefix of the objects of the version of the files uploaded under the prefix
+func VersionPrefix(prefix, version string) string {
+	return path.Join(prefix, VersionsDir, version)
+}
+
+// Versions list the versions of the files uploaded under the prefix, from the most recent to the oldest
+func Versions(s StorageI, dest, prefix string) ([]*Version, error) {
+	versionsPrefix := path.Join(prefix, VersionsDir) + "/"
+	objects, err := s.List(dest, versionsPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []*Version
+	byName := map[string]*Version{}
+	for _, object := range objects {
+		name := strings.SplitN(strings.TrimPrefix(object.Name, versionsPrefix), "/", 2)[0]
+		version, ok := byName[name]
+		if !ok {
+			version = &Version{Name: name}
+			byName[name] = version
+			versions = append(versions, version)
+		}
+		version.Objects = append(version.Objects, object.Name)
+		// the unchanged objects aren't uploaded again, only the marker dates the last use of a version
+		switch {
+		case path.Base(object.Name) == VersionMarker:
+			version.marked = true
+			version.LastModified = object.LastModified
+		case !version.marked && object.LastModified.After(version.LastModified):
+			version.LastModified = object.LastModified
+		}
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].LastModified.After(versions[j].LastModified)
+	})
+	return versions, nil
+}
+
+// MarkVersion rewrite the VersionMarker of the version of the files uploaded under the prefix, so that the
+// version is ranked as the most recent one by Versions even when none of its files was uploaded again
+func MarkVersion(s StorageI, dest, prefix, version string) error {
+	dir, err := os.MkdirTemp("", "ovh-spark-submit")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	marker := filepath.Join(dir, VersionMarker)
+	if err := os.WriteFile(marker, []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0600); err != nil {
+		return err
+	}
+	_, err = s.Upload([]string{marker}, dest, &Options{Prefix: VersionPrefix(prefix, version), Force: true})
+	return err
+}
+
+// CleanVersions delete the versions of the files uploaded under the prefix, but the current one and the most
+// recent ones, keeping keep versions in total. It returns the deleted versions
+func CleanVersions(s StorageI, dest, prefix, current string, keep int) ([]string, error) {
+	versions, err := Versions(s, dest, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := 1
+	var deleted []string
+	for _, version := range versions {
+		if version.Name == current {
+			continue
+		}
+		if kept < keep {
+			kept++
+			continue
+		}
+		if err := s.Delete(dest, version.Objects); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, version.Name)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,148 @@
+package upload
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHash(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "main.py", "lib/helpers.py")
+
+	hash, err := Hash([]string{dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hash) != hashLength {
+		t.Errorf("unexpected hash %s", hash)
+	}
+
+	// object names are hashed
+	if other, err := Hash([]string{filepath.Join(dir, "lib"), filepath.Join(dir, "main.py")}, nil); err != nil || other == hash {
+		t.Errorf("object names must be hashed: %s", other)
+	}
+
+	// same files, same hash
+	if same, err := Hash([]string{dir}, nil); err != nil || same != hash {
+		t.Errorf("hash %s isn't deterministic", same)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.py"), []byte("print('changed')"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := Hash([]string{dir}, nil); err != nil || changed == hash {
+		t.Errorf("hash must change with the content: %s", changed)
+	}
+}
+
+func TestCleanVersions(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "main.py", "lib/helpers.py")
+
+	for _, version := range []string{"run-1", "run-2", "run-3"} {
+		if _, err := s.Upload([]string{dir}, testContainer, &Options{Prefix: VersionPrefix("jobs", version), Force: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// not a version
+	if _, err := s.Upload([]string{filepath.Join(dir, "main.py")}, testContainer, &Options{Prefix: "jobs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := Versions(s, testContainer, "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || len(versions[0].Objects) != 2 {
+		t.Fatalf("unexpected versions %+v", versions)
+	}
+
+	// keep the current version and the most recent other one
+	deleted, err := CleanVersions(s, testContainer, "jobs", "run-1", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 {
+		t.Fatalf("unexpected deleted versions %q", deleted)
+	}
+
+	res := listS3Objects(t, s)
+	if len(res) != 5 || !stringInSlice("jobs/main.py", res) || !stringInSlice("jobs/_versions/run-1/main.py", res) {
+		t.Errorf("unexpected objects %q", res)
+	}
+}
+
+// listedStorage storage listing fixed objects
+type listedStorage struct {
+	objects []*Object
+	deleted []string
+}
+
+func (s *listedStorage) Upload(sources []string, dest string, options *Options) ([]string, error) {
+	return nil, nil
+}
+
+func (s *listedStorage) List(dest, prefix string) ([]*Object, error) {
+	return s.objects, nil
+}
+
+func (s *listedStorage) Delete(dest string, objects []string) error {
+	s.deleted = append(s.deleted, objects...)
+	return nil
+}
+
+func (s *listedStorage) Download(dest, object string, w io.Writer) error {
+	return nil
+}
+
+func TestVersionsMarker(t *testing.T) {
+	uploaded := time.Date(2022, 10, 7, 9, 0, 0, 0, time.UTC)
+	s := &listedStorage{objects: []*Object{
+		// uploaded first, but reused by the last run: its files were skipped and only its marker was rewritten
+		{Name: "jobs/_versions/reused/main.py", LastModified: uploaded},
+		{Name: "jobs/_versions/reused/" + VersionMarker, LastModified: uploaded.Add(2 * time.Hour)},
+		{Name: "jobs/_versions/newer/main.py", LastModified: uploaded.Add(time.Hour)},
+		{Name: "jobs/_versions/newer/" + VersionMarker, LastModified: uploaded.Add(time.Hour)},
+		// uploaded before the markers
+		{Name: "jobs/_versions/unmarked/main.py", LastModified: uploaded.Add(30 * time.Minute)},
+	}}
+
+	versions, err := Versions(s, testContainer, "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].Name != "reused" || versions[1].Name != "newer" || versions[2].Name != "unmarked" {
+		t.Fatalf("unexpected versions %+v", versions)
+	}
+
+	// the version used by a concurrent run is kept
+	deleted, err := CleanVersions(s, testContainer, "jobs", "newer", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || deleted[0] != "unmarked" || len(s.deleted) != 1 || s.deleted[0] != "jobs/_versions/unmarked/main.py" {
+		t.Errorf("unexpected deleted versions %q: %q", deleted, s.deleted)
+	}
+}
+
+func TestMarkVersion(t *testing.T) {
+	srv, s := newS3TestServer(t)
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := MarkVersion(s, testContainer, "jobs", "run-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res := listS3Objects(t, s)
+	if len(res) != 1 || res[0] != "jobs/_versions/run-1/"+VersionMarker {
+		t.Errorf("unexpected objects %q", res)
+	}
+}
@@ -0,0 +1,87 @@
+package upload
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// zipModified modification date of all the zip entries, the oldest one supported by the zip format,
+// so that the same files always give the same archive
+var zipModified = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// ZipEntry file to add in a zip archive
+type ZipEntry struct {
+	// Source path of the local file
+	Source string
+	// Name path of the file in the archive
+	Name string
+}
+
+// ZipDir list the files of the directory to add in a zip archive under the given prefix.
+// Hidden files, python caches and compiled python files are skipped
+func ZipDir(dir, prefix string) ([]*ZipEntry, error) {
+	var entries []*ZipEntry
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := d.Name()
+		if p != dir && (strings.HasPrefix(name, ".") || name == "__pycache__") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || strings.HasSuffix(name, ".pyc") || strings.HasSuffix(name, ".pyo") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, &ZipEntry{
+			Source: p,
+			Name:   path.Join(prefix, filepath.ToSlash(rel)),
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// Zip write a deterministic zip archive of the entries: they are sorted by name and
+// their dates and permissions are fixed
+func Zip(w io.Writer, entries []*ZipEntry) error {
+	sorted := make([]*ZipEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	zw := zip.NewWriter(w)
+	for _, entry := range sorted {
+		header := &zip.FileHeader{
+			Name:     entry.Name,
+			Method:   zip.Deflate,
+			Modified: zipModified,
+		}
+		header.SetMode(0644)
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if err := copyFile(fw, entry.Source); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,74 @@
+package upload
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files ...string) {
+	for _, file := range files {
+		p := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("# "+file), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestZipDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "__init__.py", "utils/helpers.py", "__pycache__/helpers.cpython-39.pyc", "utils/old.pyc", ".git/config", ".env")
+
+	entries, err := ZipDir(dir, "mypkg")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	if entries[0].Name != "mypkg/__init__.py" || entries[1].Name != "mypkg/utils/helpers.py" {
+		t.Errorf("unexpected entries %s, %s", entries[0].Name, entries[1].Name)
+	}
+}
+
+func TestZip(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, "b.py", "a.py")
+
+	entries := []*ZipEntry{
+		{Source: filepath.Join(dir, "b.py"), Name: "b.py"},
+		{Source: filepath.Join(dir, "a.py"), Name: "a.py"},
+	}
+
+	var first, second bytes.Buffer
+	if err := Zip(&first, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	// the archive doesn't depend on the modification date of the files
+	if err := os.Chtimes(filepath.Join(dir, "a.py"), zipModified, zipModified); err != nil {
+		t.Fatal(err)
+	}
+	if err := Zip(&second, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Error("zip archive isn't deterministic")
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(first.Bytes()), int64(first.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.File) != 2 || r.File[0].Name != "a.py" || r.File[1].Name != "b.py" {
+		t.Error("zip entries must be sorted by name")
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	humanize "github.com/dustin/go-humanize"
 	"github.com/gabriel-vasile/mimetype"
+	"golang.org/x/term"
 )
 
 const MinimalOverhead = 384
@@ -60,3 +61,8 @@ func DetectMimeType(filePath string) string {
 
 	return mime.String()
 }
+
+// IsTerminal test if the file is a terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
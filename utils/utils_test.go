@@ -135,3 +135,15 @@ func TestDetectMimeTypeError(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestIsTerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "not-a-terminal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if IsTerminal(f) {
+		t.Fail()
+	}
+}